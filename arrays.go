@@ -0,0 +1,77 @@
+package shutter
+
+import "github.com/ptdewey/shutter/internal/transform"
+
+// arrayRule drops or sorts the elements of JSON arrays before snapshotting.
+type arrayRule struct {
+	rule transform.ArrayRule
+}
+
+func (a *arrayRule) isOption() {}
+
+func (a *arrayRule) apply(o *snapOptions) {
+	o.arrayRules = append(o.arrayRules, a.rule)
+}
+
+// newArrayRule returns an array rule for the arrays at path, panicking if the
+// path is invalid.
+func newArrayRule(fn, path string, rule transform.ArrayRule) *arrayRule {
+	pattern, err := transform.ParsePath(path)
+	if err != nil {
+		panic("shutter: " + fn + ": " + err.Error())
+	}
+	rule.Path = pattern
+	return &arrayRule{rule: rule}
+}
+
+// SortArray sorts the elements of the arrays at a JSONPath-like path (see
+// IgnorePath for the syntax, "$" being the document itself) by their JSON
+// encoding with sorted object keys. Use it for arrays returned in
+// nondeterministic order. SortArray panics if the path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "tags", jsonStr,
+//	    shutter.SortArray("$.tags"),
+//	    shutter.SortArray("$.users[*].roles"),
+//	)
+func SortArray(path string) Option {
+	return newArrayRule("SortArray", path, transform.ArrayRule{Sort: true})
+}
+
+// SortArrayBy sorts the objects in the arrays at a JSONPath-like path by the
+// value of key: numbers numerically, strings lexically, and other values by
+// their JSON encoding. Objects without the key sort first, and ties keep
+// their order. SortArrayBy panics if the path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "users", jsonStr,
+//	    shutter.SortArrayBy("$.users", "id"),
+//	)
+func SortArrayBy(path, key string) Option {
+	return newArrayRule("SortArrayBy", path, transform.ArrayRule{Sort: true, SortKey: key})
+}
+
+// DropElements removes the elements of the arrays at a JSONPath-like path for
+// which drop returns true. drop receives elements decoded like RedactWith
+// values. Elements are dropped before any sorting, and DropElements panics if
+// the path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "events", jsonStr,
+//	    shutter.DropElements("$.events", func(value any) bool {
+//	        event, _ := value.(map[string]any)
+//	        return event["type"] == "heartbeat"
+//	    }),
+//	)
+func DropElements(path string, drop func(value any) bool) Option {
+	return newArrayRule("DropElements", path, transform.ArrayRule{Drop: drop})
+}
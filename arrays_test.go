@@ -0,0 +1,34 @@
+package shutter_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+func TestArrayOptions(t *testing.T) {
+	jsonStr := `{
+		"tags": ["zeta", "alpha", "mu"],
+		"users": [
+			{"id": 3, "name": "Cy", "roles": ["write", "admin"]},
+			{"id": 1, "name": "Ann", "roles": ["read"]},
+			{"id": 2, "name": "Bob", "roles": []}
+		],
+		"events": [
+			{"type": "login", "at": 2},
+			{"type": "heartbeat", "at": 3},
+			{"type": "logout", "at": 1}
+		]
+	}`
+
+	shutter.SnapJSON(t, "Array Options", jsonStr,
+		shutter.SortArray("$.tags"),
+		shutter.SortArray("$.users[*].roles"),
+		shutter.SortArrayBy("$.users", "id"),
+		shutter.DropElements("$.events", func(value any) bool {
+			event, _ := value.(map[string]any)
+			return event["type"] == "heartbeat"
+		}),
+		shutter.SortArrayBy("$.events", "at"),
+	)
+}
@@ -10,24 +10,51 @@ import (
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: shutter [COMMAND]
+		fmt.Fprintf(os.Stderr, `Usage: shutter [OPTIONS] [COMMAND]
 
 Commands:
   review      Review and accept/reject new snapshots (default)
   accept-all  Accept all new snapshots
   reject-all  Reject all new snapshots
+  prune       Delete snapshots not used by the last test run
+              (pass --dry-run to only list them)
+  migrate     Rewrite snapshots using the current format and layout
+              (pass --dry-run to only list them)
   help        Show this help message
 
+Options:
+  -dir DIR       Snapshot directory (default from SHUTTER_SNAPSHOT_DIR,
+                 .shutter.yaml or __snapshots__)
+  -layout NAME   Snapshot layout: flat, per-file or nested (default from
+                 SHUTTER_LAYOUT, .shutter.yaml or flat)
+
 Examples:
   shutter              # Start interactive review
   shutter review       # Same as above
   shutter accept-all   # Accept all new snapshots
   shutter reject-all   # Reject all new snapshots
+  shutter prune -n     # List obsolete snapshots without deleting
+  shutter migrate      # Upgrade snapshots after updating shutter
+  shutter -dir testdata/snapshots -layout nested review
 `)
 	}
 
+	snapshotDir := flag.String("dir", "", "snapshot directory")
+	layout := flag.String("layout", "", "snapshot layout: flat, per-file or nested")
 	flag.Parse()
 
+	if *snapshotDir != "" {
+		shutter.SetSnapshotDir(*snapshotDir)
+	}
+	if *layout != "" {
+		l, err := shutter.ParseLayout(*layout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		shutter.SetLayout(l)
+	}
+
 	var cmd string
 	if flag.NArg() > 0 {
 		cmd = flag.Arg(0)
@@ -41,6 +68,20 @@ Examples:
 		err = shutter.AcceptAll()
 	case "reject-all":
 		err = shutter.RejectAll()
+	case "prune":
+		pruneFlags := flag.NewFlagSet("prune", flag.ExitOnError)
+		pruneFlags.Usage = flag.Usage
+		dryRun := pruneFlags.Bool("dry-run", false, "list obsolete snapshots without deleting them")
+		pruneFlags.BoolVar(dryRun, "n", false, "shorthand for --dry-run")
+		pruneFlags.Parse(flag.Args()[1:])
+		err = shutter.Prune(*dryRun)
+	case "migrate":
+		migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+		migrateFlags.Usage = flag.Usage
+		dryRun := migrateFlags.Bool("dry-run", false, "list snapshots to migrate without rewriting them")
+		migrateFlags.BoolVar(dryRun, "n", false, "shorthand for --dry-run")
+		migrateFlags.Parse(flag.Args()[1:])
+		err = shutter.Migrate(*dryRun)
 	case "help", "-h", "--help":
 		flag.Usage()
 		return
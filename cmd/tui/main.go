@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -11,6 +13,7 @@ import (
 	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
 	"github.com/ptdewey/shutter/internal/pretty"
+	"github.com/ptdewey/shutter/internal/review"
 )
 
 // Styles
@@ -56,8 +59,16 @@ var (
 			Foreground(lipgloss.AdaptiveColor{Light: "8", Dark: "8"})
 )
 
+// pendingFile returns where a pending change is stored, for the footer.
+func pendingFile(item review.Item) string {
+	if item.Inline != nil {
+		return item.Inline.Location()
+	}
+	return item.Name + ".snap.new"
+}
+
 type model struct {
-	snapshots    []string
+	items        []review.Item
 	current      int
 	newSnap      *files.Snapshot
 	accepted     *files.Snapshot
@@ -76,18 +87,27 @@ type model struct {
 }
 
 func initialModel() (model, error) {
-	snapshots, err := files.ListNewSnapshots()
+	// Pending snapshots identical to the accepted ones need no review
+	redundant, err := files.ListRedundantSnapshots()
+	if err != nil {
+		return model{}, err
+	}
+	if err := files.RejectSnapshots(redundant); err != nil {
+		return model{}, err
+	}
+
+	items, err := review.PendingItems()
 	if err != nil {
 		return model{}, err
 	}
 
-	if len(snapshots) == 0 {
+	if len(items) == 0 {
 		return model{done: true}, nil
 	}
 
 	m := model{
-		snapshots: snapshots,
-		current:   0,
+		items:   items,
+		current: 0,
 	}
 
 	if err := m.loadCurrentSnapshot(); err != nil {
@@ -98,12 +118,24 @@ func initialModel() (model, error) {
 }
 
 func (m *model) loadCurrentSnapshot() error {
-	if m.current >= len(m.snapshots) {
+	if m.current >= len(m.items) {
 		m.done = true
 		return nil
 	}
 
-	testName := m.snapshots[m.current]
+	item := m.items[m.current]
+	if item.Inline != nil {
+		old, new := item.Inline.Snapshots()
+		m.newSnap = new
+		if item.Inline.Old == "" {
+			m.accepted, m.diffLines = nil, nil
+		} else {
+			m.accepted, m.diffLines = old, computeDiffLines(old, new)
+		}
+		return nil
+	}
+
+	testName := item.Name
 
 	newSnap, err := files.ReadSnapshot(testName, "new")
 	if err != nil {
@@ -112,6 +144,10 @@ func (m *model) loadCurrentSnapshot() error {
 	m.newSnap = newSnap
 
 	accepted, err := files.ReadSnapshot(testName, "accepted")
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		// Malformed or newer-format snapshots must not be silently overwritten
+		return err
+	}
 	if err == nil {
 		m.accepted = accepted
 		diffLines := computeDiffLines(accepted, newSnap)
@@ -166,8 +202,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "a":
 			// Accept current snapshot
-			testName := m.snapshots[m.current]
-			if err := files.AcceptSnapshot(testName); err != nil {
+			if err := m.items[m.current].Accept(); err != nil {
 				m.err = err
 			} else {
 				m.acceptedAll++
@@ -183,8 +218,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case "r":
 			// Reject current snapshot
-			testName := m.snapshots[m.current]
-			if err := files.RejectSnapshot(testName); err != nil {
+			if err := m.items[m.current].Reject(); err != nil {
 				m.err = err
 			} else {
 				m.rejectedAll++
@@ -211,32 +245,24 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateViewportContent()
 
 		case "A":
-			// Accept all remaining
-			for i := m.current; i < len(m.snapshots); i++ {
-				if err := files.AcceptSnapshot(m.snapshots[i]); err != nil {
-					m.err = err
-					break
-				}
-				m.acceptedAll++
-			}
+			// Accept all remaining; snapshot files all or nothing
+			count, err := review.Apply(m.items[m.current:], true)
+			m.acceptedAll += count
+			m.err = err
 			m.done = true
 			return m, tea.Quit
 
 		case "R":
-			// Reject all remaining
-			for i := m.current; i < len(m.snapshots); i++ {
-				if err := files.RejectSnapshot(m.snapshots[i]); err != nil {
-					m.err = err
-					break
-				}
-				m.rejectedAll++
-			}
+			// Reject all remaining; snapshot files all or nothing
+			count, err := review.Apply(m.items[m.current:], false)
+			m.rejectedAll += count
+			m.err = err
 			m.done = true
 			return m, tea.Quit
 
 		case "S":
 			// Skip all remaining
-			m.skippedAll = len(m.snapshots) - m.current
+			m.skippedAll = len(m.items) - m.current
 			m.done = true
 			return m, tea.Quit
 		}
@@ -296,7 +322,7 @@ func (m *model) updateViewportContent() {
 
 func (m model) View() string {
 	if m.done {
-		if len(m.snapshots) == 0 {
+		if len(m.items) == 0 {
 			return pretty.Success("✓ No new snapshots to review\n")
 		}
 
@@ -327,19 +353,22 @@ func (m model) View() string {
 	}
 
 	// Header
-	snapshotTitle := m.snapshots[m.current] // fallback to test name
+	snapshotTitle := m.items[m.current].Title() // fallback to test name
 	if m.newSnap != nil && m.newSnap.Title != "" {
 		snapshotTitle = m.newSnap.Title
 	}
+	if m.newSnap != nil && m.newSnap.Description != "" {
+		snapshotTitle += " - " + m.newSnap.Description
+	}
 	header := lipgloss.JoinHorizontal(
 		lipgloss.Left,
 		titleStyle.Render("Review Snapshots"),
-		counterStyle.Render(fmt.Sprintf("[%d/%d] %s", m.current+1, len(m.snapshots), snapshotTitle)),
+		counterStyle.Render(fmt.Sprintf("[%d/%d] %s", m.current+1, len(m.items), snapshotTitle)),
 	)
 	headerStyled := statusBarStyle.Width(m.width).Render(header)
 
 	// Footer with snapshot filename and scroll info
-	snapshotFile := files.SnapshotFileName(m.snapshots[m.current]) + ".snap.new"
+	snapshotFile := pendingFile(m.items[m.current])
 	fileInfo := helpStyle.Render(snapshotFile)
 	scrollInfo := fmt.Sprintf("%3.f%%", m.viewport.ScrollPercent()*100)
 	scrollStyled := helpStyle.Render(scrollInfo)
@@ -369,70 +398,63 @@ func (m model) View() string {
 	)
 }
 
-func acceptAll() error {
-	snapshots, err := files.ListNewSnapshots()
-	if err != nil {
-		return err
-	}
+// usage is the help text printed by "help" and for invalid flags.
+const usage = `Usage: shutter-tui [OPTIONS] [COMMAND]
 
-	for _, testName := range snapshots {
-		if err := files.AcceptSnapshot(testName); err != nil {
-			return err
-		}
-	}
+Commands:
+  review      Review and accept/reject new snapshots (default)
+  accept-all  Accept all new snapshots
+  reject-all  Reject all new snapshots
+  help        Show this help message
 
-	fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), len(snapshots))
-	return nil
-}
+Options:
+  -dir DIR     Snapshot directory (default from SHUTTER_SNAPSHOT_DIR,
+               .shutter.yaml or __snapshots__)
+  -layout NAME Snapshot layout: flat, per-file or nested
 
-func rejectAll() error {
-	snapshots, err := files.ListNewSnapshots()
-	if err != nil {
-		return err
-	}
+Interactive Controls:
+  a           Accept current snapshot
+  r           Reject current snapshot
+  s           Skip current snapshot
+  A           Accept all remaining snapshots
+  R           Reject all remaining snapshots
+  S           Skip all remaining snapshots
+  q           Quit`
 
-	for _, testName := range snapshots {
-		if err := files.RejectSnapshot(testName); err != nil {
-			return err
+func main() {
+	snapshotDir := flag.String("dir", "", "snapshot directory")
+	layout := flag.String("layout", "", "snapshot layout: flat, per-file or nested")
+	flag.Usage = func() { fmt.Println(usage) }
+	flag.Parse()
+
+	if *snapshotDir != "" {
+		files.SetSnapshotDir(*snapshotDir)
+	}
+	if *layout != "" {
+		l, err := files.ParseLayout(*layout)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		files.SetLayout(l)
 	}
 
-	fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(snapshots))
-	return nil
-}
-
-func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
 		case "accept-all":
-			if err := acceptAll(); err != nil {
+			if err := review.AcceptAll(); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 			return
 		case "reject-all":
-			if err := rejectAll(); err != nil {
+			if err := review.RejectAll(); err != nil {
 				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 				os.Exit(1)
 			}
 			return
 		case "help", "-h", "--help":
-			fmt.Println(`Usage: shutter-tui [COMMAND]
-
-Commands:
-  review      Review and accept/reject new snapshots (default)
-  accept-all  Accept all new snapshots
-  reject-all  Reject all new snapshots
-  help        Show this help message
-
-Interactive Controls:
-  a           Accept current snapshot
-  r           Reject current snapshot
-  s           Skip current snapshot
-  A           Accept all remaining snapshots
-  R           Reject all remaining snapshots
-  S           Skip all remaining snapshots
-  q           Quit`)
+			flag.Usage()
 			return
 		}
 	}
@@ -443,7 +465,7 @@ Interactive Controls:
 		os.Exit(1)
 	}
 
-	if m.done && len(m.snapshots) == 0 {
+	if m.done && len(m.items) == 0 {
 		fmt.Println(m.View())
 		return
 	}
@@ -4,6 +4,8 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+
+	"github.com/ptdewey/shutter/internal/transform"
 )
 
 // exactKeyValueIgnore ignores exact key-value matches.
@@ -130,6 +132,54 @@ func IgnoreKeyMatching(pattern string) IgnorePattern {
 	}
 }
 
+// pathIgnore ignores the values at JSONPath-like paths.
+type pathIgnore struct {
+	patterns []*transform.PathPattern
+}
+
+func (p *pathIgnore) isOption() {}
+
+// ShouldIgnore never matches: path patterns are applied by location instead.
+func (p *pathIgnore) ShouldIgnore(key, value string) bool {
+	return false
+}
+
+// IgnorePath creates an ignore pattern that removes the values at the given
+// JSONPath-like paths, leaving keys of the same name elsewhere untouched.
+// Paths start with "$" for the document root, followed by any of:
+//
+//	.name or ['name']  an object key
+//	[n]                an array index
+//	.* or [*]          any key or array element
+//	..name             name at any depth below
+//
+// Matching array elements are removed from their array. IgnorePath panics if
+// a path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.IgnorePath("$.meta.request_id", "$.users[*].created_at"),
+//	)
+func IgnorePath(paths ...string) IgnorePattern {
+	patterns := make([]*transform.PathPattern, len(paths))
+	for i, path := range paths {
+		pattern, err := transform.ParsePath(path)
+		if err != nil {
+			panic("shutter: IgnorePath: " + err.Error())
+		}
+		if pattern.IsRoot() {
+			panic("shutter: IgnorePath: the document root cannot be ignored")
+		}
+		patterns[i] = pattern
+	}
+	return &pathIgnore{
+		patterns: patterns,
+	}
+}
+
 // Common ignore patterns for sensitive data
 var sensitiveKeys = []string{
 	"password", "secret", "token", "api_key", "apiKey",
@@ -221,6 +221,39 @@ func TestNestedIgnorePatterns(t *testing.T) {
 	)
 }
 
+func TestIgnorePath(t *testing.T) {
+	jsonStr := `{
+		"meta": {"request_id": "req_123", "id": "kept"},
+		"request_id": "kept at the root",
+		"users": [
+			{"id": 1, "name": "Ann", "created_at": "2024-01-01"},
+			{"id": 2, "name": "Bob", "created_at": "2024-01-02"}
+		],
+		"debug": [{"trace": "a"}, {"trace": "b"}],
+		"items": ["first", "second", "third"],
+		"odd.key": {"secret": "x"}
+	}`
+
+	shutter.SnapJSON(t, "Ignore Paths", jsonStr,
+		shutter.IgnorePath("$.meta.request_id", "$.users[*].created_at"),
+		shutter.IgnorePath("$..trace", "$.items[1]", "$['odd.key'].secret"),
+		shutter.IgnoreKey("name"),
+	)
+}
+
+func TestIgnorePath_InvalidPathPanics(t *testing.T) {
+	for _, path := range []string{"meta.id", "$", "$.users[", "$.users[x]", "$.", "$.."} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected IgnorePath(%q) to panic", path)
+				}
+			}()
+			shutter.IgnorePath(path)
+		}()
+	}
+}
+
 func TestCombinedIgnoreAndScrub(t *testing.T) {
 	jsonStr := `{
 		"user_id": "550e8400-e29b-41d4-a716-446655440000",
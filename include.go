@@ -0,0 +1,59 @@
+package shutter
+
+import "github.com/ptdewey/shutter/internal/transform"
+
+// include keeps only the selected JSON values before snapshotting.
+type include struct {
+	patterns []*transform.PathPattern
+}
+
+func (i *include) isOption() {}
+
+func (i *include) apply(o *snapOptions) {
+	o.includes = append(o.includes, i.patterns...)
+}
+
+// IncludeOnly creates an option that keeps only the values at the given
+// JSONPath-like paths (see IgnorePath for the syntax), along with the objects
+// and arrays containing them, and removes everything else. Array elements
+// with nothing to keep are removed. Several IncludeOnly and IncludeOnlyKeys
+// options keep everything any of them selects. Ignore patterns, redactions
+// and scrubbers apply to what is kept. IncludeOnly panics if a path is
+// invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.IncludeOnly("$.user.name", "$.items[*].id"),
+//	)
+func IncludeOnly(paths ...string) Option {
+	patterns := make([]*transform.PathPattern, len(paths))
+	for i, path := range paths {
+		pattern, err := transform.ParsePath(path)
+		if err != nil {
+			panic("shutter: IncludeOnly: " + err.Error())
+		}
+		patterns[i] = pattern
+	}
+	return &include{patterns: patterns}
+}
+
+// IncludeOnlyKeys is like IncludeOnly, keeping the values of every key with
+// one of the given names, at any depth.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.IncludeOnlyKeys("id", "status"),
+//	)
+func IncludeOnlyKeys(keys ...string) Option {
+	patterns := make([]*transform.PathPattern, len(keys))
+	for i, key := range keys {
+		patterns[i] = transform.KeyPath(key)
+	}
+	return &include{patterns: patterns}
+}
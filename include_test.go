@@ -0,0 +1,32 @@
+package shutter_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+func TestIncludeOnly(t *testing.T) {
+	jsonStr := `{
+		"user": {
+			"id": "u-1",
+			"name": "Ann",
+			"email": "ann@example.com",
+			"password": "hunter2",
+			"preferences": {"theme": "dark", "status": "beta"}
+		},
+		"items": [
+			{"id": 1, "price": 5, "status": "shipped"},
+			{"id": 2, "price": 7, "status": "pending"}
+		],
+		"links": {"self": "/orders/1"},
+		"took_ms": 12
+	}`
+
+	shutter.SnapJSON(t, "Include Only", jsonStr,
+		shutter.IncludeOnly("$.user", "$.items[*].id"),
+		shutter.IncludeOnlyKeys("status"),
+		shutter.IgnoreSensitive(),
+		shutter.ScrubEmail(),
+	)
+}
@@ -0,0 +1,67 @@
+package diff
+
+import "strings"
+
+const (
+	sectionPrefix = "=== "
+	sectionSuffix = " ==="
+)
+
+// SectionHeader returns the line starting a named section of snapshot content.
+func SectionHeader(name string) string {
+	return sectionPrefix + name + sectionSuffix
+}
+
+// SectionName returns the name of the section started by line, if line is a
+// section header.
+func SectionName(line string) (string, bool) {
+	if len(line) < len(sectionPrefix)+len(sectionSuffix) ||
+		!strings.HasPrefix(line, sectionPrefix) || !strings.HasSuffix(line, sectionSuffix) {
+		return "", false
+	}
+	name := line[len(sectionPrefix) : len(line)-len(sectionSuffix)]
+	if strings.TrimSpace(name) == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// Section is a run of diff lines belonging to one section of the content.
+type Section struct {
+	// Name is empty for lines before the first section header.
+	Name string
+	// Lines starts with the section header, if any.
+	Lines []DiffLine
+}
+
+// Changed reports whether the section contains added or removed lines.
+func (s Section) Changed() bool {
+	for _, dl := range s.Lines {
+		if dl.Kind != DiffShared {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitSections groups diff lines by the section they belong to. It returns
+// nil if the content has no section headers.
+func SplitSections(lines []DiffLine) []Section {
+	var sections []Section
+	current := Section{}
+
+	for _, dl := range lines {
+		if name, ok := SectionName(dl.Line); ok {
+			if len(current.Lines) > 0 {
+				sections = append(sections, current)
+			}
+			current = Section{Name: name}
+		}
+		current.Lines = append(current.Lines, dl)
+	}
+
+	if len(sections) == 0 && current.Name == "" {
+		return nil
+	}
+	return append(sections, current)
+}
@@ -0,0 +1,60 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/diff"
+)
+
+func TestSectionName(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		ok   bool
+	}{
+		{diff.SectionHeader("request"), "request", true},
+		{"=== two words ===", "two words", true},
+		{"=== ===", "", false},
+		{"=== request", "", false},
+		{"request ===", "", false},
+		{"plain line", "", false},
+	}
+
+	for _, tt := range tests {
+		name, ok := diff.SectionName(tt.line)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("SectionName(%q) = %q, %v, want %q, %v", tt.line, name, ok, tt.name, tt.ok)
+		}
+	}
+}
+
+func TestSplitSections(t *testing.T) {
+	old := "preamble\n=== request ===\nGET /\n=== response ===\n200"
+	new := "preamble\n=== request ===\nGET /\n=== response ===\n404"
+
+	sections := diff.SplitSections(diff.Histogram(old, new))
+	if len(sections) != 3 {
+		t.Fatalf("expected 3 sections, got %d", len(sections))
+	}
+
+	want := []struct {
+		name    string
+		lines   int
+		changed bool
+	}{
+		{"", 1, false},
+		{"request", 2, false},
+		{"response", 3, true},
+	}
+	for i, w := range want {
+		s := sections[i]
+		if s.Name != w.name || len(s.Lines) != w.lines || s.Changed() != w.changed {
+			t.Errorf("section %d = {%q, %d lines, changed %v}, want {%q, %d lines, changed %v}",
+				i, s.Name, len(s.Lines), s.Changed(), w.name, w.lines, w.changed)
+		}
+	}
+
+	if got := diff.SplitSections(diff.Histogram("a\nb", "a\nc")); got != nil {
+		t.Errorf("expected no sections for plain content, got %v", got)
+	}
+}
@@ -0,0 +1,146 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// lockSuffix is appended to a snapshot path to form its lock file.
+	lockSuffix = ".lock"
+	// lockTimeout is how long to wait for a lock held by another process.
+	lockTimeout = 10 * time.Second
+	// staleLockAge is the age after which a lock file is assumed to belong
+	// to a process that died without releasing it.
+	staleLockAge = 30 * time.Second
+	// lockRetryInterval is how long to sleep between attempts to take a lock.
+	lockRetryInterval = 5 * time.Millisecond
+)
+
+var (
+	// fileLocks serializes access to each snapshot path within this process.
+	fileLocks sync.Map // map[string]*sync.Mutex
+	// createdDirs caches directories already created by this process.
+	createdDirs sync.Map // map[string]bool
+)
+
+// ensureDir creates dir if this process has not already done so.
+func ensureDir(dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := createdDirs.Load(absDir); ok {
+		return nil
+	}
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return err
+	}
+	createdDirs.Store(absDir, true)
+	return nil
+}
+
+// lockPath takes an exclusive lock on path, shared between goroutines of this
+// process and, through a lock file next to path, with other processes such
+// as a concurrent review session. The returned function releases the lock.
+func lockPath(path string) (func(), error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _ := fileLocks.LoadOrStore(absPath, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+
+	lockFile := absPath + lockSuffix
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(lockFile)
+				mu.Unlock()
+			}, nil
+		}
+
+		if errors.Is(err, os.ErrNotExist) {
+			// The directory does not exist yet
+			if err := os.MkdirAll(filepath.Dir(lockFile), 0755); err != nil {
+				mu.Unlock()
+				return nil, err
+			}
+			continue
+		}
+		if !errors.Is(err, os.ErrExist) {
+			mu.Unlock()
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockFile); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(lockFile)
+			continue
+		}
+		if time.Now().After(deadline) {
+			mu.Unlock()
+			return nil, fmt.Errorf("timed out waiting for lock %s; remove it if no other shutter process is running", lockFile)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers see either the old or the new contents
+// and never a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if errors.Is(err, os.ErrNotExist) {
+		// The directory was removed since it was created
+		if absDir, err := filepath.Abs(dir); err == nil {
+			createdDirs.Delete(absDir)
+		}
+		if err := ensureDir(dir); err != nil {
+			return err
+		}
+		f, err = os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	}
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	committed := false
+	defer func() {
+		if !committed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
@@ -0,0 +1,186 @@
+package files_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/snapshots"
+)
+
+// largeContent returns content big enough that a torn write would be visible.
+func largeContent(i int) string {
+	return strings.Repeat(fmt.Sprintf("writer %03d\n", i), 2000)
+}
+
+func TestSaveSnapshot_ConcurrentWritersAndReaders(t *testing.T) {
+	chdirTemp(t)
+
+	const writers = 200
+	const readers = 50
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers+readers)
+
+	// Writers alternate between their own snapshot and one shared by all
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			own := &files.Snapshot{Title: fmt.Sprintf("own %d", i), Test: "TestConcurrent", Content: largeContent(i)}
+			if err := files.SaveSnapshot(own, "accepted"); err != nil {
+				errs <- err
+				return
+			}
+
+			shared := &files.Snapshot{Title: "shared", Test: "TestConcurrent", Content: largeContent(i)}
+			if err := files.SaveSnapshot(shared, "accepted"); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
+	// Readers must only ever see complete snapshots
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 20 {
+				snap, err := files.ReadAccepted("shared")
+				if os.IsNotExist(err) {
+					continue
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(snap.Content) != len(largeContent(0)) {
+					errs <- fmt.Errorf("read partially written snapshot (%d bytes)", len(snap.Content))
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for i := range writers {
+		snap, err := files.ReadAccepted(files.SnapshotFileName(fmt.Sprintf("own %d", i)))
+		if err != nil {
+			t.Fatalf("failed to read snapshot %d: %v", i, err)
+		}
+		if snap.Content != largeContent(i) {
+			t.Fatalf("snapshot %d has wrong content", i)
+		}
+	}
+
+	entries, err := os.ReadDir("__snapshots__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".snap") {
+			t.Errorf("unexpected leftover file %s", entry.Name())
+		}
+	}
+}
+
+func TestAcceptSnapshot_ConcurrentWithSave(t *testing.T) {
+	chdirTemp(t)
+
+	const rounds = 100
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds)
+
+	for i := range rounds {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			snap := &files.Snapshot{Title: "contended", Test: "TestAccept", Content: largeContent(i)}
+			if err := files.SaveSnapshot(snap, "new"); err != nil {
+				errs <- err
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// The pending snapshot may already have been accepted by another goroutine
+			if err := files.AcceptSnapshot("contended"); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs <- err
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	snap, err := files.ReadAccepted("contended")
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("failed to read accepted snapshot: %v", err)
+	}
+	if err == nil && len(snap.Content) != len(largeContent(0)) {
+		t.Errorf("accepted snapshot is incomplete (%d bytes)", len(snap.Content))
+	}
+
+	if _, err := os.Stat(filepath.Join("__snapshots__", "contended.lock")); !os.IsNotExist(err) {
+		t.Errorf("expected lock file to be released, got %v", err)
+	}
+}
+
+func TestSnap_ParallelSubtests(t *testing.T) {
+	chdirTemp(t)
+	files.ResetVisited()
+
+	const subtests = 300
+
+	// Accept every snapshot up front, so that the subtests only fail on a
+	// real problem. Each subtest snapshots its title twice.
+	var titles []string
+	for i := range subtests {
+		title := fmt.Sprintf("parallel %d", i)
+		titles = append(titles, title, title+"#01")
+	}
+	for _, title := range titles {
+		snap := &files.Snapshot{Title: title, Test: "TestSnap_ParallelSubtests", Content: "value", Version: files.FormatVersion}
+		if err := files.SaveSnapshot(snap, "accepted"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("group", func(t *testing.T) {
+		for i := range subtests {
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				t.Parallel()
+				title := fmt.Sprintf("parallel %d", i)
+				snapshots.Snap(t, title, files.FormatVersion, "value")
+				snapshots.Snap(t, title, files.FormatVersion, "value")
+			})
+		}
+	})
+
+	if err := files.CompleteVisited(); err != nil {
+		t.Fatal(err)
+	}
+	visited, err := files.ReadVisited()
+	if err != nil {
+		t.Fatalf("ReadVisited failed: %v", err)
+	}
+	if len(visited) != len(titles) {
+		t.Errorf("expected %d visited snapshots, got %d", len(titles), len(visited))
+	}
+}
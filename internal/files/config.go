@@ -0,0 +1,321 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Layout controls how snapshot files are arranged inside the snapshot directory.
+type Layout int
+
+const (
+	// LayoutFlat stores every snapshot directly in the snapshot directory.
+	LayoutFlat Layout = iota
+	// LayoutPerFile stores snapshots in one subdirectory per test file,
+	// named after the file without its _test.go suffix.
+	LayoutPerFile
+	// LayoutNested stores snapshots in directories mirroring the t.Name()
+	// subtest hierarchy.
+	LayoutNested
+)
+
+const (
+	// DefaultSnapshotDir is the snapshot directory used when none is configured.
+	DefaultSnapshotDir = "__snapshots__"
+	// SnapshotDirEnvVar is the environment variable used to select the snapshot directory.
+	SnapshotDirEnvVar = "SHUTTER_SNAPSHOT_DIR"
+	// LayoutEnvVar is the environment variable used to select the layout.
+	LayoutEnvVar = "SHUTTER_LAYOUT"
+	// ConfigFileName is the name of the optional configuration file. It is
+	// looked up from the package directory upwards to the module root.
+	ConfigFileName = ".shutter.yaml"
+)
+
+var (
+	configMu sync.RWMutex
+	// dirOverride and layoutOverride are set from code and take precedence
+	// over the environment and the configuration file.
+	dirOverride    string
+	layoutOverride *Layout
+	// fileConfigs caches the configuration file settings by working
+	// directory, so the file is only looked up and parsed once.
+	fileConfigs = map[string]fileConfig{}
+)
+
+func (l Layout) String() string {
+	switch l {
+	case LayoutFlat:
+		return "flat"
+	case LayoutPerFile:
+		return "per-file"
+	case LayoutNested:
+		return "nested"
+	default:
+		return fmt.Sprintf("Layout(%d)", int(l))
+	}
+}
+
+// ParseLayout parses the textual form of a layout as accepted by the
+// SHUTTER_LAYOUT environment variable.
+func ParseLayout(s string) (Layout, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "flat":
+		return LayoutFlat, nil
+	case "per-file", "file":
+		return LayoutPerFile, nil
+	case "nested":
+		return LayoutNested, nil
+	default:
+		return LayoutFlat, fmt.Errorf("invalid layout %q (expected flat, per-file or nested)", s)
+	}
+}
+
+// SetSnapshotDir overrides the snapshot directory for the current process.
+// Relative paths are resolved against the package directory. An empty dir
+// removes the override.
+func SetSnapshotDir(dir string) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	dirOverride = dir
+	clear(fileConfigs)
+}
+
+// SetLayout overrides the snapshot layout for the current process.
+func SetLayout(layout Layout) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	layoutOverride = &layout
+	clear(fileConfigs)
+}
+
+// resetConfig clears overrides set with SetSnapshotDir and SetLayout.
+func resetConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	dirOverride = ""
+	layoutOverride = nil
+	clear(fileConfigs)
+}
+
+// config is the resolved snapshot storage configuration.
+type config struct {
+	dir    string
+	layout Layout
+}
+
+// fileConfig is the result of reading the configuration file.
+type fileConfig struct {
+	cfg config
+	err error
+}
+
+// loadConfig resolves the configuration from, in order of precedence, values
+// set from code, environment variables, the configuration file and defaults.
+func loadConfig() (config, error) {
+	cfg, err := loadConfigFile()
+	if err != nil {
+		return cfg, err
+	}
+
+	if dir := os.Getenv(SnapshotDirEnvVar); dir != "" {
+		cfg.dir = dir
+	}
+	if value := os.Getenv(LayoutEnvVar); value != "" {
+		layout, err := ParseLayout(value)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %w", LayoutEnvVar, err)
+		}
+		cfg.layout = layout
+	}
+
+	configMu.RLock()
+	defer configMu.RUnlock()
+	if dirOverride != "" {
+		cfg.dir = dirOverride
+	}
+	if layoutOverride != nil {
+		cfg.layout = *layoutOverride
+	}
+
+	return cfg, nil
+}
+
+// loadConfigFile returns the defaults with the configuration file applied.
+// The result is cached per working directory until SetSnapshotDir or
+// SetLayout is called.
+func loadConfigFile() (config, error) {
+	cfg := config{dir: DefaultSnapshotDir, layout: LayoutFlat}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return cfg, err
+	}
+
+	configMu.RLock()
+	cached, ok := fileConfigs[wd]
+	configMu.RUnlock()
+	if ok {
+		return cached.cfg, cached.err
+	}
+
+	err = readConfigFile(&cfg, wd)
+
+	configMu.Lock()
+	fileConfigs[wd] = fileConfig{cfg: cfg, err: err}
+	configMu.Unlock()
+
+	return cfg, err
+}
+
+// findConfigFile returns the path of the closest configuration file between
+// dir and the module root, or "" if there is none.
+func findConfigFile(dir string) string {
+	for {
+		candidate := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+
+		// Stop at the module root
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// readConfigFile applies the settings in the configuration file found from
+// dir, if any. The file holds "key: value" lines; blank lines and # comments
+// are ignored.
+func readConfigFile(cfg *config, dir string) error {
+	configPath := findConfigFile(dir)
+	if configPath == "" {
+		return nil
+	}
+
+	f, err := os.Open(configPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(line, ":")
+		if !ok {
+			return &ParseError{File: configPath, Line: lineNum, Msg: fmt.Sprintf("malformed line %q", line)}
+		}
+
+		value, err := parseScalar(raw)
+		if err != nil {
+			return &ParseError{File: configPath, Line: lineNum, Msg: err.Error()}
+		}
+
+		switch strings.TrimSpace(key) {
+		case "snapshot_dir":
+			if value == "" {
+				return &ParseError{File: configPath, Line: lineNum, Msg: "snapshot_dir must not be empty"}
+			}
+			cfg.dir = value
+		case "layout":
+			layout, err := ParseLayout(value)
+			if err != nil {
+				return &ParseError{File: configPath, Line: lineNum, Msg: err.Error()}
+			}
+			cfg.layout = layout
+		default:
+			return &ParseError{File: configPath, Line: lineNum, Msg: fmt.Sprintf("unknown setting %q", key)}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// SnapshotDir returns the configured snapshot directory, without creating it.
+func SnapshotDir() (string, error) {
+	cfg, err := loadConfig()
+	return filepath.Clean(cfg.dir), err
+}
+
+// CurrentLayout returns the configured snapshot layout.
+func CurrentLayout() (Layout, error) {
+	cfg, err := loadConfig()
+	return cfg.layout, err
+}
+
+// SnapshotName returns the name a snapshot is stored under: its path inside
+// the snapshot directory, using "/" as separator and without extension. It
+// depends on the configured layout.
+func SnapshotName(snap *Snapshot) (string, error) {
+	if err := ValidateTitle(snap.Title); err != nil {
+		return "", err
+	}
+
+	layout, err := CurrentLayout()
+	if err != nil {
+		return "", err
+	}
+
+	return layoutName(layout, snap), nil
+}
+
+// layoutName returns the name of snap under layout.
+func layoutName(layout Layout, snap *Snapshot) string {
+	switch layout {
+	case LayoutPerFile:
+		return testFileDir(snap.FileName) + "/" + SnapshotFileName(snap.Title)
+	case LayoutNested:
+		if snap.Test == "" {
+			return SnapshotFileName(snap.Title)
+		}
+		return nestedName(snap.Test, snap.Title)
+	default:
+		return SnapshotFileName(snap.Title)
+	}
+}
+
+// testFileDir returns the directory used for a test file in LayoutPerFile.
+func testFileDir(fileName string) string {
+	base := strings.TrimSuffix(filepath.Base(fileName), ".go")
+	base = strings.TrimSuffix(base, "_test")
+	if base == "" || base == "." {
+		base = "unknown"
+	}
+	return SnapshotFileName(base)
+}
+
+// nestedName returns the name of a snapshot in LayoutNested. Directories
+// mirror the test name; titles derived from the test name are not repeated.
+func nestedName(testName, title string) string {
+	dirs := strings.Split(testName, "/")
+	file := title
+
+	switch {
+	case title == testName:
+		dirs, file = dirs[:len(dirs)-1], dirs[len(dirs)-1]
+	case strings.HasPrefix(title, testName+"/"):
+		file = strings.TrimPrefix(title, testName+"/")
+	}
+
+	segments := make([]string, 0, len(dirs)+1)
+	for _, dir := range dirs {
+		segments = append(segments, SnapshotFileName(dir))
+	}
+	segments = append(segments, SnapshotFileName(file))
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,207 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestParseLayout(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    files.Layout
+		wantErr bool
+	}{
+		{"", files.LayoutFlat, false},
+		{"flat", files.LayoutFlat, false},
+		{"per-file", files.LayoutPerFile, false},
+		{"FILE", files.LayoutPerFile, false},
+		{"nested", files.LayoutNested, false},
+		{"tree", files.LayoutFlat, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := files.ParseLayout(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLayout(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLayout(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotName_Layouts(t *testing.T) {
+	chdirTemp(t)
+	t.Cleanup(files.ResetConfig)
+
+	snap := &files.Snapshot{Title: "Response Body", Test: "TestUser/alice", FileName: "user_test.go"}
+	auto := &files.Snapshot{Title: "TestUser/alice", Test: "TestUser/alice", FileName: "user_test.go"}
+
+	tests := []struct {
+		layout files.Layout
+		snap   *files.Snapshot
+		want   string
+	}{
+		{files.LayoutFlat, snap, "response_body"},
+		{files.LayoutFlat, auto, "testuser__alice"},
+		{files.LayoutPerFile, snap, "user/response_body"},
+		{files.LayoutPerFile, auto, "user/testuser__alice"},
+		{files.LayoutNested, snap, "testuser/alice/response_body"},
+		{files.LayoutNested, auto, "testuser/alice"},
+	}
+
+	for _, tt := range tests {
+		files.SetLayout(tt.layout)
+		got, err := files.SnapshotName(tt.snap)
+		if err != nil {
+			t.Fatalf("SnapshotName(%q) under %v failed: %v", tt.snap.Title, tt.layout, err)
+		}
+		if got != tt.want {
+			t.Errorf("SnapshotName(%q) under %v = %q, want %q", tt.snap.Title, tt.layout, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotDir_Precedence(t *testing.T) {
+	tmpDir := chdirTemp(t)
+	t.Cleanup(files.ResetConfig)
+	t.Setenv(files.SnapshotDirEnvVar, "")
+
+	if dir, _ := files.SnapshotDir(); dir != files.DefaultSnapshotDir {
+		t.Errorf("expected default dir, got %q", dir)
+	}
+
+	// The config file is found from a package directory below the module root
+	if err := os.WriteFile(filepath.Join(tmpDir, "go.mod"), []byte("module example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := "# shutter settings\nsnapshot_dir: testdata/snapshots\nlayout: nested\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, files.ConfigFileName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pkgDir := filepath.Join(tmpDir, "pkg")
+	if err := os.Mkdir(pkgDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(pkgDir)
+
+	if dir, _ := files.SnapshotDir(); dir != filepath.FromSlash("testdata/snapshots") {
+		t.Errorf("expected dir from config file, got %q", dir)
+	}
+	if layout, _ := files.CurrentLayout(); layout != files.LayoutNested {
+		t.Errorf("expected layout from config file, got %v", layout)
+	}
+
+	t.Setenv(files.SnapshotDirEnvVar, "from-env")
+	if dir, _ := files.SnapshotDir(); dir != "from-env" {
+		t.Errorf("expected dir from environment, got %q", dir)
+	}
+
+	files.SetSnapshotDir("from-code")
+	if dir, _ := files.SnapshotDir(); dir != "from-code" {
+		t.Errorf("expected dir set from code, got %q", dir)
+	}
+}
+
+func TestConfigFile_ReadOncePerDirectory(t *testing.T) {
+	tmpDir := chdirTemp(t)
+	t.Cleanup(files.ResetConfig)
+
+	configPath := filepath.Join(tmpDir, files.ConfigFileName)
+	if err := os.WriteFile(configPath, []byte("layout: nested\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if layout, _ := files.CurrentLayout(); layout != files.LayoutNested {
+		t.Fatalf("expected layout from config file, got %v", layout)
+	}
+
+	// Later edits are not picked up until the configuration is reset
+	if err := os.WriteFile(configPath, []byte("layout: per-file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if layout, _ := files.CurrentLayout(); layout != files.LayoutNested {
+		t.Errorf("expected cached layout, got %v", layout)
+	}
+
+	files.ResetConfig()
+	if layout, _ := files.CurrentLayout(); layout != files.LayoutPerFile {
+		t.Errorf("expected layout to be read again after a reset, got %v", layout)
+	}
+}
+
+func TestConfigFile_Invalid(t *testing.T) {
+	tmpDir := chdirTemp(t)
+
+	if err := os.WriteFile(filepath.Join(tmpDir, files.ConfigFileName), []byte("layout: sideways\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := files.CurrentLayout()
+	var parseErr *files.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 1 {
+		t.Errorf("expected a ParseError on line 1, got %v", err)
+	}
+}
+
+func TestNestedLayout_ReviewRoundTrip(t *testing.T) {
+	chdirTemp(t)
+	t.Cleanup(files.ResetConfig)
+	files.SetSnapshotDir("testdata/snapshots")
+	files.SetLayout(files.LayoutNested)
+
+	snap := &files.Snapshot{Title: "TestUser/alice", Test: "TestUser/alice", Content: "content\n"}
+	if err := files.SaveSnapshot(snap, "new"); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	names, err := files.ListNewSnapshots()
+	if err != nil {
+		t.Fatalf("ListNewSnapshots failed: %v", err)
+	}
+	if !slices.Equal(names, []string{"testuser/alice"}) {
+		t.Fatalf("unexpected pending snapshots: %q", names)
+	}
+
+	if err := files.AcceptSnapshot(names[0]); err != nil {
+		t.Fatalf("AcceptSnapshot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("testdata", "snapshots", "testuser", "alice.snap")); err != nil {
+		t.Errorf("expected accepted snapshot in nested directory: %v", err)
+	}
+
+	if err := files.DeleteSnapshot(names[0]); err != nil {
+		t.Fatalf("DeleteSnapshot failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("testdata", "snapshots", "testuser")); !os.IsNotExist(err) {
+		t.Errorf("expected empty directory to be removed, got %v", err)
+	}
+}
+
+func TestMigrate_ChangesLayout(t *testing.T) {
+	chdirTemp(t)
+	t.Cleanup(files.ResetConfig)
+
+	snap := &files.Snapshot{Title: "Body", Test: "TestUser/alice", FileName: "user_test.go", Version: files.FormatVersion}
+	if err := files.SaveSnapshot(snap, "accepted"); err != nil {
+		t.Fatal(err)
+	}
+
+	files.SetLayout(files.LayoutPerFile)
+	if _, err := files.Migrate(false); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	if _, err := files.ReadAccepted("user/body"); err != nil {
+		t.Errorf("expected snapshot to move into the per-file layout: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join("__snapshots__", "body.snap")); !os.IsNotExist(err) {
+		t.Errorf("expected flat snapshot to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package files
+
+import (
+	"os"
+	"testing"
+)
+
+// ResetConfig clears overrides set with SetSnapshotDir and SetLayout.
+func ResetConfig() {
+	resetConfig()
+}
+
+// FailNthRename makes the n-th rename while applying a transaction fail with
+// err, until the test ends.
+func FailNthRename(t *testing.T, n int, err error) {
+	calls := 0
+	applyRename = func(from, to string) error {
+		calls++
+		if calls == n {
+			return err
+		}
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { applyRename = os.Rename })
+}
@@ -1,87 +1,217 @@
 package files
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 )
 
+// FormatVersion is the version of the snapshot file format written by this
+// package. It is bumped when the header or naming scheme changes in a way
+// older readers would misread, but not for new optional header fields.
+const FormatVersion = "0.2.0"
+
 type Snapshot struct {
 	Version  string
 	Title    string
 	Test     string
 	FileName string
+	// Line is the line in FileName that took the snapshot, or 0 if unknown.
+	Line int
+	// Expression is the source of the snapshotted value, or empty if unknown.
+	Expression string
+	// Description explains what the snapshot shows to reviewers.
+	Description string
+	// Metadata holds arbitrary key/value pairs describing the snapshot, such
+	// as the input that produced it.
+	Metadata map[string]string
+	// Sections is set for content split into "=== name ===" sections by
+	// SnapSections. Only such content is grouped by section when reviewing.
+	Sections bool
 	Content  string
 }
 
-func (s *Snapshot) Serialize() string {
-	header := fmt.Sprintf(
-		"---\ntitle: %s\ntest_name: %s\nfile_name: %s\nversion: %s\n---\n",
-		s.Title, s.Test, s.FileName, s.Version,
-	)
-	return header + s.Content
+// errInvalidName is returned for snapshot names that would escape the
+// snapshot directory.
+var errInvalidName = errors.New("invalid snapshot name")
+
+// legacyHeaderFields were written by early versions and are accepted but ignored.
+var legacyHeaderFields = map[string]bool{
+	"file_path": true,
+	"func_name": true,
 }
 
-func Deserialize(raw string) (*Snapshot, error) {
-	parts := strings.SplitN(raw, "---\n", 3)
-	if len(parts) < 3 {
-		return nil, fmt.Errorf("invalid snapshot format")
+// Serialize encodes the snapshot as a YAML-style header followed by its content.
+// Header values are quoted whenever needed to round-trip them exactly.
+func (s *Snapshot) Serialize() string {
+	var sb strings.Builder
+	sb.WriteString("---\n")
+	writeField(&sb, "title", s.Title)
+	writeField(&sb, "test_name", s.Test)
+	writeField(&sb, "file_name", s.FileName)
+	if s.Line > 0 {
+		sb.WriteString("line: " + strconv.Itoa(s.Line) + "\n")
+	}
+	if s.Expression != "" {
+		writeField(&sb, "expression", s.Expression)
+	}
+	if s.Description != "" {
+		writeField(&sb, "description", s.Description)
 	}
+	if len(s.Metadata) > 0 {
+		sb.WriteString("metadata:\n")
+		for _, key := range slices.Sorted(maps.Keys(s.Metadata)) {
+			sb.WriteString("  " + formatScalar(key) + ": " + formatScalar(s.Metadata[key]) + "\n")
+		}
+	}
+	if s.Sections {
+		sb.WriteString("sections: true\n")
+	}
+	writeField(&sb, "version", s.Version)
+	sb.WriteString("---\n")
+	sb.WriteString(s.Content)
+	return sb.String()
+}
 
-	header := parts[1]
-	content := parts[2]
+func writeField(sb *strings.Builder, key, value string) {
+	sb.WriteString(key + ": " + formatScalar(value) + "\n")
+}
 
-	snap := &Snapshot{
-		Content: content,
+// Deserialize parses a serialized snapshot. Errors are *ParseError values
+// carrying the line number of the offending header line.
+func Deserialize(raw string) (*Snapshot, error) {
+	if !strings.HasPrefix(raw, "---\n") {
+		return nil, &ParseError{Line: 1, Msg: "invalid snapshot format: expected opening '---'"}
 	}
 
-	for line := range strings.SplitSeq(header, "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
+	snap := &Snapshot{}
+	seen := map[string]bool{}
+	rest := raw[len("---\n"):]
+	versionLine := 0
+	inMetadata := false
+	var unknown *ParseError
+
+	for lineNum := 2; ; lineNum++ {
+		line, remaining, found := strings.Cut(rest, "\n")
+		if !found {
+			return nil, &ParseError{Line: lineNum, Msg: "invalid snapshot format: missing closing '---'"}
+		}
+		rest = remaining
+
+		line = strings.TrimSuffix(line, "\r")
+		if line == "---" {
+			if unknown != nil {
+				// Unknown fields are expected in a newer format; report the version instead
+				if CompareVersions(snap.Version, FormatVersion) > 0 {
+					return nil, newerFormatError(versionLine, snap.Version)
+				}
+				return nil, unknown
+			}
+			snap.Content = rest
+			return snap, nil
+		}
+		if strings.TrimSpace(line) == "" {
 			continue
 		}
 
-		kv := strings.SplitN(line, ": ", 2)
-		if len(kv) != 2 {
+		if line[0] == ' ' || line[0] == '\t' {
+			if !inMetadata {
+				if unknown == nil {
+					unknown = &ParseError{Line: lineNum, Msg: fmt.Sprintf("unexpected indented header line %q", line)}
+				}
+				continue
+			}
+			key, value, err := parseMetadataEntry(strings.TrimLeft(line, " \t"))
+			if err != nil {
+				return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("metadata: %v", err)}
+			}
+			if _, ok := snap.Metadata[key]; ok {
+				return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("duplicate metadata key %q", key)}
+			}
+			snap.Metadata[key] = value
 			continue
 		}
+		inMetadata = false
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !isHeaderKey(key) {
+			return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("malformed header line %q", line)}
+		}
+		if seen[key] {
+			return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("duplicate header field %q", key)}
+		}
+		seen[key] = true
+
+		parsed, err := parseScalar(value)
+		if err != nil {
+			return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("header field %q: %v", key, err)}
+		}
 
-		key, value := kv[0], kv[1]
 		switch key {
 		case "title":
-			snap.Title = value
+			snap.Title = parsed
 		case "test_name":
-			snap.Test = value
+			snap.Test = parsed
 		case "file_name":
-			snap.FileName = value
+			snap.FileName = parsed
+		case "line":
+			line, err := strconv.Atoi(parsed)
+			if err != nil || line <= 0 {
+				return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("header field %q: invalid line number %q", key, parsed)}
+			}
+			snap.Line = line
+		case "expression":
+			snap.Expression = parsed
+		case "description":
+			snap.Description = parsed
+		case "sections":
+			if parsed != "true" {
+				return nil, &ParseError{Line: lineNum, Msg: fmt.Sprintf("header field %q: expected true, got %q", key, parsed)}
+			}
+			snap.Sections = true
+		case "metadata":
+			if parsed != "" {
+				return nil, &ParseError{Line: lineNum, Msg: "header field \"metadata\": expected indented key/value lines"}
+			}
+			snap.Metadata = map[string]string{}
+			inMetadata = true
 		case "version":
-			snap.Version = value
+			snap.Version = parsed
+			versionLine = lineNum
+		default:
+			if !legacyHeaderFields[key] && unknown == nil {
+				unknown = &ParseError{Line: lineNum, Msg: fmt.Sprintf("unknown header field %q", key)}
+			}
 		}
 	}
-
-	return snap, nil
 }
 
 func getSnapshotDir() (string, error) {
-	// NOTE: maybe this could be configurable?
-	// Storing snapshots in root may be desirable in some cases
-	snapshotDir := "__snapshots__"
-	if err := os.MkdirAll(snapshotDir, 0755); err != nil {
+	snapshotDir, err := SnapshotDir()
+	if err != nil {
+		return "", err
+	}
+
+	if err := ensureDir(snapshotDir); err != nil {
 		return "", err
 	}
 
 	return snapshotDir, nil
 }
 
-// TODO: make this use the snapshot title rather than the test name
-func SnapshotFileName(snapTitle string) string {
-	return strings.ReplaceAll(strings.ToLower(snapTitle), " ", "_")
+// getSnapshotFileName returns the filename for a snapshot based on its name and state
+func getSnapshotFileName(name string, state string) string {
+	return withStateSuffix(filepath.FromSlash(name), state)
 }
 
-// getSnapshotFileName returns the filename for a snapshot based on test name and state
-func getSnapshotFileName(snapTitle string, state string) string {
-	baseName := SnapshotFileName(snapTitle)
+// withStateSuffix appends the extension for state to a base file name
+func withStateSuffix(baseName string, state string) string {
 	switch state {
 	case "accepted":
 		return baseName + ".snap"
@@ -92,104 +222,249 @@ func getSnapshotFileName(snapTitle string, state string) string {
 	}
 }
 
-// getSnapshotPath returns the full path for a snapshot file
-func getSnapshotPath(snapTitle string, state string) (string, error) {
-	snapshotDir, err := getSnapshotDir()
+// getSnapshotPath returns the full path for a snapshot file, without creating
+// any directories. Names use "/" to separate directories; see SnapshotName.
+func getSnapshotPath(name string, state string) (string, error) {
+	if err := ValidateTitle(name); err != nil {
+		return "", err
+	}
+
+	snapshotDir, err := SnapshotDir()
 	if err != nil {
 		return "", err
 	}
 
-	fileName := getSnapshotFileName(snapTitle, state)
+	fileName := getSnapshotFileName(name, state)
+
+	// Defense in depth: escaping should make this impossible
+	if !filepath.IsLocal(fileName) {
+		return "", fmt.Errorf("%w %q: file would be outside %s", errInvalidName, name, snapshotDir)
+	}
+
 	return filepath.Join(snapshotDir, fileName), nil
 }
 
-func SaveSnapshot(snap *Snapshot, state string) error {
+// SnapshotPath returns the path of the file storing the snapshot name in the
+// given state ("accepted" or "new").
+func SnapshotPath(name, state string) (string, error) {
+	return getSnapshotPath(name, state)
+}
+
+// lockSnapshot takes the lock guarding all files of the snapshot stored under
+// name. See lockPath.
+func lockSnapshot(name string) (func(), error) {
 	snapshotDir, err := getSnapshotDir()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	fileName := getSnapshotFileName(snap.Title, state)
-	filePath := filepath.Join(snapshotDir, fileName)
+	return lockPath(filepath.Join(snapshotDir, filepath.FromSlash(name)))
+}
+
+// LegacyPath returns the path of an accepted snapshot for snap stored under
+// the name an earlier naming scheme gave its title, if there is one. Such
+// files are only renamed by Migrate.
+func LegacyPath(snap *Snapshot) (string, bool) {
+	layout, err := CurrentLayout()
+	if err != nil || layout != LayoutFlat {
+		// Other layouts were introduced together with the current scheme
+		return "", false
+	}
 
-	return os.WriteFile(filePath, []byte(snap.Serialize()), 0644)
+	for _, name := range legacyFileNames(snap.Title) {
+		filePath, err := getSnapshotPath(name, "accepted")
+		if err != nil {
+			continue
+		}
+		// The name may belong to another title under the current scheme
+		if legacy, err := readSnapshotFile(filePath); err == nil && legacy.Title == snap.Title {
+			return filePath, true
+		}
+	}
+	return "", false
 }
 
-func ReadSnapshot(snapTitle string, state string) (*Snapshot, error) {
-	snapshotDir, err := getSnapshotDir()
+// SaveSnapshot writes snap under the name given by SnapshotName.
+func SaveSnapshot(snap *Snapshot, state string) error {
+	name, err := SnapshotName(snap)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	fileName := getSnapshotFileName(snapTitle, state)
-	filePath := filepath.Join(snapshotDir, fileName)
-
-	data, err := os.ReadFile(filePath)
+	filePath, err := getSnapshotPath(name, state)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return Deserialize(string(data))
-}
+	if err := ensureDir(filepath.Dir(filePath)); err != nil {
+		return err
+	}
 
-func ReadAccepted(snapTitle string) (*Snapshot, error) {
-	return ReadSnapshot(snapTitle, "snap")
-}
+	unlock, err := lockSnapshot(name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-func ReadNew(snapTitle string) (*Snapshot, error) {
-	return ReadSnapshot(snapTitle, "new")
+	return writeFileAtomic(filePath, []byte(snap.Serialize()))
 }
 
-func ListNewSnapshots() ([]string, error) {
-	snapshotDir, err := getSnapshotDir()
+// ReadSnapshot reads the snapshot stored under name in the given state.
+// Snapshot files are replaced atomically, so a partially written file is
+// never observed.
+func ReadSnapshot(name string, state string) (*Snapshot, error) {
+	filePath, err := getSnapshotPath(name, state)
 	if err != nil {
 		return nil, err
 	}
 
-	entries, err := os.ReadDir(snapshotDir)
+	return readSnapshotFile(filePath)
+}
+
+// readSnapshotFile reads and parses the snapshot file at filePath.
+func readSnapshotFile(filePath string) (*Snapshot, error) {
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var newSnapshots []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".snap.new") {
-			name := strings.TrimSuffix(entry.Name(), ".snap.new")
-			newSnapshots = append(newSnapshots, name)
-		}
+	snap, err := Deserialize(string(data))
+	var parseErr *ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.File = filePath
 	}
+	return snap, err
+}
+
+func ReadAccepted(name string) (*Snapshot, error) {
+	return ReadSnapshot(name, "accepted")
+}
 
-	return newSnapshots, nil
+func ReadNew(name string) (*Snapshot, error) {
+	return ReadSnapshot(name, "new")
 }
 
-func AcceptSnapshot(snapTitle string) error {
-	newPath, err := getSnapshotPath(snapTitle, "new")
+// RemoveStaleNew removes the pending snapshot stored under name, if any, and
+// reports whether there was one. It is used when a test matches its accepted
+// snapshot again, which makes the pending file from an earlier run obsolete.
+func RemoveStaleNew(name string) (bool, error) {
+	filePath, err := getSnapshotPath(name, "new")
 	if err != nil {
-		return err
+		return false, err
+	}
+
+	// Avoid taking the lock in the common case of no pending file
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return false, nil
 	}
 
-	acceptedPath, err := getSnapshotPath(snapTitle, "accepted")
+	unlock, err := lockSnapshot(name)
 	if err != nil {
-		return err
+		return false, err
+	}
+	defer unlock()
+
+	err = os.Remove(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
 	}
+	return err == nil, err
+}
 
-	data, err := os.ReadFile(newPath)
+// ListRedundantSnapshots returns the names of pending snapshots whose content
+// is identical to the accepted snapshot, so that reviewing them is pointless.
+func ListRedundantSnapshots() ([]string, error) {
+	pending, err := ListNewSnapshots()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := os.WriteFile(acceptedPath, data, 0644); err != nil {
-		return err
+	var redundant []string
+	for _, name := range pending {
+		newSnap, err := ReadNew(name)
+		if err != nil {
+			// Left for review, which reports the error
+			continue
+		}
+		accepted, err := ReadAccepted(name)
+		if err != nil {
+			continue
+		}
+		if newSnap.Content == accepted.Content {
+			redundant = append(redundant, name)
+		}
 	}
 
-	return os.Remove(newPath)
+	return redundant, nil
 }
 
-func RejectSnapshot(snapTitle string) error {
-	filePath, err := getSnapshotPath(snapTitle, "new")
+// snapshotStates maps snapshot file extensions to their state.
+var snapshotStates = []struct {
+	suffix string
+	state  string
+}{
+	// .snap.new must come first since it also ends in .snap
+	{".snap.new", "new"},
+	{".snap", "accepted"},
+}
+
+// walkSnapshots calls fn with the name, state and path of every snapshot file
+// in the snapshot directory, including subdirectories.
+func walkSnapshots(fn func(name, state, filePath string) error) error {
+	snapshotDir, err := getSnapshotDir()
 	if err != nil {
 		return err
 	}
 
-	return os.Remove(filePath)
+	return filepath.WalkDir(snapshotDir, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, os.ErrNotExist) {
+			// Removed while walking, e.g. by a concurrent prune
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), txDirPrefix) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(snapshotDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		for _, s := range snapshotStates {
+			if strings.HasSuffix(rel, s.suffix) {
+				return fn(strings.TrimSuffix(rel, s.suffix), s.state, path)
+			}
+		}
+		return nil
+	})
+}
+
+// ListNewSnapshots returns the names of all pending snapshots.
+func ListNewSnapshots() ([]string, error) {
+	var newSnapshots []string
+	err := walkSnapshots(func(name, state, _ string) error {
+		if state == "new" {
+			newSnapshots = append(newSnapshots, name)
+		}
+		return nil
+	})
+	return newSnapshots, err
+}
+
+// AcceptSnapshot replaces the accepted snapshot stored under name with the
+// pending one.
+func AcceptSnapshot(name string) error {
+	return AcceptSnapshots([]string{name})
+}
+
+// RejectSnapshot removes the pending snapshot stored under name.
+func RejectSnapshot(name string) error {
+	return RejectSnapshots([]string{name})
 }
@@ -1,8 +1,6 @@
 package files_test
 
 import (
-	"os"
-	"path/filepath"
 	"testing"
 
 	"github.com/ptdewey/shutter/internal/files"
@@ -147,6 +145,8 @@ func TestDeserializeValidFormats(t *testing.T) {
 }
 
 func TestSaveAndReadSnapshot(t *testing.T) {
+	chdirTemp(t)
+
 	snap := &files.Snapshot{
 		Title:   "Save Read Title",
 		Test:    "TestSaveRead",
@@ -157,7 +157,7 @@ func TestSaveAndReadSnapshot(t *testing.T) {
 		t.Fatalf("SaveSnapshot failed: %v", err)
 	}
 
-	read, err := files.ReadSnapshot("TestSaveRead", "test")
+	read, err := files.ReadSnapshot(files.SnapshotFileName("Save Read Title"), "test")
 	if err != nil {
 		t.Fatalf("ReadSnapshot failed: %v", err)
 	}
@@ -165,8 +165,6 @@ func TestSaveAndReadSnapshot(t *testing.T) {
 	if read.Content != snap.Content {
 		t.Errorf("Content mismatch: %s != %s", read.Content, snap.Content)
 	}
-
-	cleanupSnapshot(t, "TestSaveRead", "test")
 }
 
 func TestReadSnapshotNotFound(t *testing.T) {
@@ -177,6 +175,8 @@ func TestReadSnapshotNotFound(t *testing.T) {
 }
 
 func TestAcceptSnapshot(t *testing.T) {
+	chdirTemp(t)
+
 	newSnap := &files.Snapshot{
 		Title:   "Accept Title",
 		Test:    "TestAccept",
@@ -187,11 +187,11 @@ func TestAcceptSnapshot(t *testing.T) {
 		t.Fatalf("SaveSnapshot failed: %v", err)
 	}
 
-	if err := files.AcceptSnapshot("Accept Title"); err != nil {
+	if err := files.AcceptSnapshot(files.SnapshotFileName("Accept Title")); err != nil {
 		t.Fatalf("AcceptSnapshot failed: %v", err)
 	}
 
-	accepted, err := files.ReadSnapshot("TestAccept", "accepted")
+	accepted, err := files.ReadSnapshot(files.SnapshotFileName("Accept Title"), "accepted")
 	if err != nil {
 		t.Fatalf("ReadSnapshot failed: %v", err)
 	}
@@ -200,15 +200,15 @@ func TestAcceptSnapshot(t *testing.T) {
 		t.Errorf("Content mismatch: %s != %s", accepted.Content, newSnap.Content)
 	}
 
-	_, err = files.ReadSnapshot("TestAccept", "new")
+	_, err = files.ReadSnapshot(files.SnapshotFileName("Accept Title"), "new")
 	if err == nil {
 		t.Error("expected error: .new file should be deleted after accept")
 	}
-
-	cleanupSnapshot(t, "TestAccept", "accepted")
 }
 
 func TestRejectSnapshot(t *testing.T) {
+	chdirTemp(t)
+
 	snap := &files.Snapshot{
 		Title:   "Reject Title",
 		Test:    "TestReject",
@@ -219,33 +219,46 @@ func TestRejectSnapshot(t *testing.T) {
 		t.Fatalf("SaveSnapshot failed: %v", err)
 	}
 
-	if err := files.RejectSnapshot("Reject Title"); err != nil {
+	if err := files.RejectSnapshot(files.SnapshotFileName("Reject Title")); err != nil {
 		t.Fatalf("RejectSnapshot failed: %v", err)
 	}
 
-	_, err := files.ReadSnapshot("Reject Title", "new")
+	_, err := files.ReadSnapshot(files.SnapshotFileName("Reject Title"), "new")
 	if err == nil {
 		t.Error("expected error: .new file should be deleted after reject")
 	}
 }
 
-func cleanupSnapshot(t *testing.T, testName, state string) {
-	t.Helper()
+func TestListRedundantSnapshots(t *testing.T) {
+	chdirTemp(t)
+
+	saveSnap(t, "same", "content", "accepted")
+	saveSnap(t, "same", "content", "new")
+	saveSnap(t, "changed", "old", "accepted")
+	saveSnap(t, "changed", "new", "new")
+	saveSnap(t, "unseen", "content", "new")
 
-	root, err := os.Getwd()
+	redundant, err := files.ListRedundantSnapshots()
 	if err != nil {
-		t.Logf("cleanup: failed to get cwd: %v", err)
-		return
+		t.Fatalf("ListRedundantSnapshots failed: %v", err)
 	}
+	if len(redundant) != 1 || redundant[0] != "same" {
+		t.Errorf("expected [same], got %v", redundant)
+	}
+}
+
+func TestRemoveStaleNew(t *testing.T) {
+	chdirTemp(t)
 
-	for root != "/" && root != "" {
-		if _, err := os.Stat(filepath.Join(root, "go.mod")); err == nil {
-			break
+	saveSnap(t, "stale", "content", "new")
+
+	for _, want := range []bool{true, false} {
+		removed, err := files.RemoveStaleNew("stale")
+		if err != nil {
+			t.Fatalf("RemoveStaleNew failed: %v", err)
+		}
+		if removed != want {
+			t.Errorf("removed = %v, want %v", removed, want)
 		}
-		root = filepath.Dir(root)
 	}
-
-	fileName := files.SnapshotFileName(testName) + "." + state
-	filePath := filepath.Join(root, "__snapshots__", fileName)
-	_ = os.Remove(filePath)
 }
@@ -0,0 +1,201 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrNewerFormat is wrapped by errors for snapshots written by a newer,
+// unsupported version of the snapshot format.
+var ErrNewerFormat = errors.New("snapshot written by a newer format")
+
+// ParseError describes a problem with a snapshot file's header.
+type ParseError struct {
+	File string // path of the snapshot file, if known
+	Line int    // 1-based line number
+	Msg  string
+	Err  error // underlying cause, such as ErrNewerFormat
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// yamlIndicators are characters that change the meaning of a plain YAML
+// scalar when they appear first.
+const yamlIndicators = "-?:,[]{}#&*!|>'\"%@`"
+
+// needsQuoting reports whether value must be quoted to be read back verbatim
+// by both Deserialize and a YAML parser.
+func needsQuoting(value string) bool {
+	if value == "" || strings.TrimSpace(value) != value {
+		return true
+	}
+	if strings.ContainsAny(value[:1], yamlIndicators) {
+		return true
+	}
+	if strings.Contains(value, ": ") || strings.Contains(value, " #") || strings.HasSuffix(value, ":") {
+		return true
+	}
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return true
+		}
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return true
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return true
+	}
+
+	return false
+}
+
+// formatScalar returns value as a plain or double-quoted YAML scalar.
+func formatScalar(value string) string {
+	if needsQuoting(value) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// parseScalar parses a plain, single-quoted or double-quoted YAML scalar.
+func parseScalar(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+
+	switch raw[0] {
+	case '"':
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted value %s", raw)
+		}
+		return value, nil
+	case '\'':
+		if len(raw) < 2 || raw[len(raw)-1] != '\'' {
+			return "", fmt.Errorf("unterminated single-quoted value %s", raw)
+		}
+		inner := raw[1 : len(raw)-1]
+		if strings.Contains(strings.ReplaceAll(inner, "''", ""), "'") {
+			return "", fmt.Errorf("invalid single-quoted value %s", raw)
+		}
+		return strings.ReplaceAll(inner, "''", "'"), nil
+	}
+
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
+
+// parseMetadataEntry parses a "key: value" metadata line, where both key and
+// value are plain or quoted scalars.
+func parseMetadataEntry(line string) (string, string, error) {
+	var key, rest string
+	switch line[0] {
+	case '"':
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid double-quoted key in %q", line)
+		}
+		key, _ = strconv.Unquote(quoted)
+		rest = line[len(quoted):]
+	case '\'':
+		end := 1
+		for end < len(line) {
+			if line[end] == '\'' {
+				if end+1 < len(line) && line[end+1] == '\'' {
+					end += 2
+					continue
+				}
+				break
+			}
+			end++
+		}
+		if end >= len(line) {
+			return "", "", fmt.Errorf("unterminated single-quoted key in %q", line)
+		}
+		key = strings.ReplaceAll(line[1:end], "''", "'")
+		rest = line[end+1:]
+	default:
+		i := strings.Index(line, ": ")
+		if i < 0 {
+			if !strings.HasSuffix(line, ":") {
+				return "", "", fmt.Errorf("malformed line %q", line)
+			}
+			i = len(line) - 1
+		}
+		key, rest = strings.TrimSpace(line[:i]), line[i:]
+	}
+
+	value, ok := strings.CutPrefix(rest, ":")
+	if !ok {
+		return "", "", fmt.Errorf("malformed line %q", line)
+	}
+	parsed, err := parseScalar(value)
+	if err != nil {
+		return "", "", fmt.Errorf("key %q: %v", key, err)
+	}
+	return key, parsed, nil
+}
+
+// isHeaderKey reports whether key is a well-formed header field name.
+func isHeaderKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareVersions compares two snapshot format versions of the form
+// "major.minor.patch", returning -1, 0 or 1. Missing components count as 0,
+// so snapshots written before versions were recorded compare as oldest.
+func CompareVersions(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var na, nb int
+		if i < len(pa) {
+			na, _ = strconv.Atoi(pa[i])
+		}
+		if i < len(pb) {
+			nb, _ = strconv.Atoi(pb[i])
+		}
+		if na != nb {
+			if na < nb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// newerFormatError reports a snapshot written by a newer format version.
+func newerFormatError(line int, version string) *ParseError {
+	return &ParseError{
+		Line: line,
+		Msg: fmt.Sprintf("snapshot format %s is newer than the supported format %s; upgrade shutter to read it",
+			version, FormatVersion),
+		Err: ErrNewerFormat,
+	}
+}
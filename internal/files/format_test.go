@@ -0,0 +1,245 @@
+package files_test
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestSerialize_RoundTripsAnyTitle(t *testing.T) {
+	titles := []string{
+		"plain title",
+		"title with\nnewline",
+		`"quoted" title`,
+		"'single' quotes",
+		"  leading and trailing  ",
+		"---",
+		"key: value",
+		"hash # comment",
+		"ends with colon:",
+		"- list item",
+		"true",
+		"123",
+		"",
+		"tab\tand\x00nul",
+		"emoji 🎉 and ünïcödé",
+		"TestUser#01",
+	}
+
+	for _, title := range titles {
+		t.Run(title, func(t *testing.T) {
+			snap := &files.Snapshot{
+				Title:    title,
+				Test:     title,
+				FileName: "example_test.go",
+				Version:  files.FormatVersion,
+				Content:  "content",
+			}
+
+			got, err := files.Deserialize(snap.Serialize())
+			if err != nil {
+				t.Fatalf("Deserialize failed: %v\n%s", err, snap.Serialize())
+			}
+			if got.Title != title || got.Test != title {
+				t.Errorf("round trip mismatch: got title %q, test %q, want %q", got.Title, got.Test, title)
+			}
+		})
+	}
+}
+
+func TestSerialize_QuotesOnlyWhenNeeded(t *testing.T) {
+	snap := &files.Snapshot{Title: "Plain Title", Test: "multi\nline", Version: "0.2.0"}
+
+	serialized := snap.Serialize()
+	for _, want := range []string{"title: Plain Title\n", "test_name: \"multi\\nline\"\n", "file_name: \"\"\n", "version: 0.2.0\n"} {
+		if !strings.Contains(serialized, want) {
+			t.Errorf("expected %q in serialized header:\n%s", want, serialized)
+		}
+	}
+}
+
+func TestDeserialize_ContentStartingWithSeparator(t *testing.T) {
+	snap := &files.Snapshot{
+		Title:   "separator",
+		Content: "---\nnot: a header\n---\nstill content",
+	}
+
+	got, err := files.Deserialize(snap.Serialize())
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if got.Content != snap.Content {
+		t.Errorf("Content = %q, want %q", got.Content, snap.Content)
+	}
+}
+
+func TestDeserialize_SingleQuotedValues(t *testing.T) {
+	raw := "---\ntitle: 'it''s quoted'\n---\ncontent"
+
+	got, err := files.Deserialize(raw)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if got.Title != "it's quoted" {
+		t.Errorf("Title = %q, want %q", got.Title, "it's quoted")
+	}
+}
+
+func TestSerialize_Source(t *testing.T) {
+	snap := &files.Snapshot{Title: "located", FileName: "user_test.go", Line: 42, Version: files.FormatVersion}
+
+	serialized := snap.Serialize()
+	if !strings.Contains(serialized, "file_name: user_test.go\nline: 42\n") {
+		t.Errorf("expected the line after the file name, got:\n%s", serialized)
+	}
+
+	got, err := files.Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if got.Line != 42 {
+		t.Errorf("Line = %d, want 42", got.Line)
+	}
+
+	// Expressions are quoted as needed
+	snap.Expression = "render(t, []string{\"a\", \"b\"})"
+	got, err = files.Deserialize(snap.Serialize())
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if got.Expression != snap.Expression {
+		t.Errorf("Expression = %q, want %q", got.Expression, snap.Expression)
+	}
+
+	// Snapshots with an unknown line do not record it
+	snap.Line = 0
+	if strings.Contains(snap.Serialize(), "line:") {
+		t.Errorf("expected no line field, got:\n%s", snap.Serialize())
+	}
+}
+
+func TestSerialize_Info(t *testing.T) {
+	snap := &files.Snapshot{
+		Title:       "described",
+		Description: "GET /users/123 as an admin",
+		Metadata: map[string]string{
+			"input":       "{\"id\": \"123\"}",
+			"key: tricky": "multi\nline",
+			"'quoted'":    "",
+			"plain:key":   "value # not a comment",
+		},
+		Version: files.FormatVersion,
+	}
+
+	serialized := snap.Serialize()
+	if !strings.Contains(serialized, "metadata:\n  \"'quoted'\": \"\"\n  input: ") {
+		t.Errorf("expected sorted, indented metadata, got:\n%s", serialized)
+	}
+
+	got, err := files.Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v\n%s", err, serialized)
+	}
+	if got.Description != snap.Description {
+		t.Errorf("Description = %q, want %q", got.Description, snap.Description)
+	}
+	if !maps.Equal(got.Metadata, snap.Metadata) {
+		t.Errorf("Metadata = %q, want %q", got.Metadata, snap.Metadata)
+	}
+
+	// Hand-written single-quoted keys are accepted too
+	got, err = files.Deserialize("---\ntitle: x\nmetadata:\n  'it''s': yes\nversion: 0.2.0\n---\n")
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if got.Metadata["it's"] != "yes" || got.Version != "0.2.0" {
+		t.Errorf("unexpected snapshot %+v", got)
+	}
+}
+
+func TestSerialize_Sections(t *testing.T) {
+	snap := &files.Snapshot{Title: "sectioned", Sections: true, Version: files.FormatVersion, Content: "=== a ===\n1\n"}
+
+	serialized := snap.Serialize()
+	if !strings.Contains(serialized, "\nsections: true\n") {
+		t.Errorf("expected a sections field, got:\n%s", serialized)
+	}
+
+	got, err := files.Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Deserialize failed: %v", err)
+	}
+	if !got.Sections {
+		t.Error("expected Sections to round-trip")
+	}
+
+	snap.Sections = false
+	if strings.Contains(snap.Serialize(), "sections:") {
+		t.Error("expected no sections field for plain content")
+	}
+}
+
+func TestDeserialize_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantLine int
+		wantMsg  string
+	}{
+		{"missing opening", "title: x\n---\n", 1, "opening"},
+		{"missing closing", "---\ntitle: x\n", 3, "closing"},
+		{"malformed line", "---\ntitle: x\nnot a field\n---\n", 3, "malformed"},
+		{"unknown field", "---\ntitle: x\nversion: 0.1.0\ncolour: blue\n---\n", 4, "unknown header field \"colour\""},
+		{"duplicate field", "---\ntitle: x\ntitle: y\n---\n", 3, "duplicate"},
+		{"bad quoting", "---\ntitle: \"unterminated\n---\n", 2, "double-quoted"},
+		{"bad single quoting", "---\ntitle: 'it's'\n---\n", 2, "single-quoted"},
+		{"bad line number", "---\ntitle: x\nline: twelve\n---\n", 3, "invalid line number"},
+		{"inline metadata", "---\ntitle: x\nmetadata: a\n---\n", 3, "expected indented key/value lines"},
+		{"duplicate metadata key", "---\ntitle: x\nmetadata:\n  a: 1\n  a: 2\n---\n", 5, "duplicate metadata key \"a\""},
+		{"malformed metadata", "---\ntitle: x\nmetadata:\n  novalue\n---\n", 4, "metadata: malformed line"},
+		{"stray indented line", "---\ntitle: x\n  a: 1\n---\n", 3, "unexpected indented header line"},
+		{"bad sections flag", "---\ntitle: x\nsections: yes\n---\n", 3, "expected true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := files.Deserialize(tt.input)
+
+			var parseErr *files.ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("expected *ParseError, got %v", err)
+			}
+			if parseErr.Line != tt.wantLine {
+				t.Errorf("Line = %d, want %d (%v)", parseErr.Line, tt.wantLine, err)
+			}
+			if !strings.Contains(parseErr.Msg, tt.wantMsg) {
+				t.Errorf("expected message containing %q, got %q", tt.wantMsg, parseErr.Msg)
+			}
+		})
+	}
+}
+
+func TestReadSnapshot_ErrorIncludesFileName(t *testing.T) {
+	chdirTemp(t)
+
+	if err := os.MkdirAll("__snapshots__", 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("__snapshots__", "broken.snap")
+	if err := os.WriteFile(path, []byte("---\ntitle: broken\nbogus\n---\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := files.ReadAccepted("broken")
+	if err == nil {
+		t.Fatal("expected error for malformed snapshot")
+	}
+	if !strings.HasPrefix(err.Error(), path+":3:") {
+		t.Errorf("expected error to start with %q, got %q", path+":3:", err.Error())
+	}
+}
@@ -0,0 +1,142 @@
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// inlineSuffix is the extension of pending inline snapshot updates.
+const inlineSuffix = ".inline.new"
+
+// InlineUpdate is a pending change to an inline snapshot. Inline snapshots
+// store their accepted value as a string literal in the test source, so only
+// pending updates are kept in the snapshot directory.
+type InlineUpdate struct {
+	File string `json:"file"` // source file, relative to the package directory
+	Line int    `json:"line"` // line of the SnapInline call
+	Test string `json:"test"`
+	Old  string `json:"old"` // expected value found in the source
+	New  string `json:"new"` // value produced by the test run
+}
+
+// Location returns the call site as "file:line".
+func (u *InlineUpdate) Location() string {
+	return fmt.Sprintf("%s:%d", u.File, u.Line)
+}
+
+// Snapshots returns the expected and new values as snapshots, for display in
+// diff boxes.
+func (u *InlineUpdate) Snapshots() (old, new *Snapshot) {
+	old = &Snapshot{Title: u.Location(), Test: u.Test, FileName: filepath.Base(u.File), Content: u.Old + "\n"}
+	new = &Snapshot{Title: u.Location(), Test: u.Test, FileName: filepath.Base(u.File), Content: u.New + "\n"}
+	return old, new
+}
+
+// getInlinePath returns the path of the pending record for an inline update,
+// without creating the snapshot directory
+func getInlinePath(u *InlineUpdate) (string, error) {
+	snapshotDir, err := SnapshotDir()
+	if err != nil {
+		return "", err
+	}
+
+	name := SnapshotFileName(fmt.Sprintf("%s_%d", filepath.Base(u.File), u.Line))
+	return filepath.Join(snapshotDir, name+inlineSuffix), nil
+}
+
+// SaveInline records a pending inline update, replacing any earlier record for
+// the same call site.
+func SaveInline(u *InlineUpdate) error {
+	filePath, err := getInlinePath(u)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := ensureDir(filepath.Dir(filePath)); err != nil {
+		return err
+	}
+
+	unlock, err := lockPath(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return writeFileAtomic(filePath, append(data, '\n'))
+}
+
+// RemoveInline deletes the pending record for an inline update. It is not an
+// error if no record exists.
+func RemoveInline(u *InlineUpdate) error {
+	filePath, err := getInlinePath(u)
+	if err != nil {
+		return err
+	}
+
+	// Avoid taking the lock in the common case of no pending record
+	if _, err := os.Stat(filePath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	unlock, err := lockPath(filePath)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ListInline returns all pending inline updates, ordered by file and line.
+func ListInline() ([]*InlineUpdate, error) {
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var updates []*InlineUpdate
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), inlineSuffix) {
+			continue
+		}
+
+		filePath := filepath.Join(snapshotDir, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var u InlineUpdate
+		if err := json.Unmarshal(data, &u); err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+		updates = append(updates, &u)
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		if updates[i].File != updates[j].File {
+			return updates[i].File < updates[j].File
+		}
+		return updates[i].Line < updates[j].Line
+	})
+
+	return updates, nil
+}
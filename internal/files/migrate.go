@@ -0,0 +1,141 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Migration describes a snapshot file rewritten by Migrate.
+type Migration struct {
+	From        string // path of the existing file
+	To          string // path of the migrated file, equal to From unless renamed
+	FromVersion string // format version the file was written with
+}
+
+// Migrate rewrites every snapshot in the snapshot directory using the current
+// header format and file naming scheme. All files are parsed before anything
+// is written, and the changes are applied in a single transaction, so the
+// directory is either fully migrated or left untouched. When dryRun is true,
+// the migrations are computed but not applied.
+func Migrate(dryRun bool) ([]Migration, error) {
+	layout, err := CurrentLayout()
+	if err != nil {
+		return nil, err
+	}
+
+	// Begin first, so that an interrupted transaction is rolled back before
+	// the files are read
+	tx, err := Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	type pending struct {
+		migration Migration
+		fromName  string
+		toName    string
+		state     string
+		data      string
+	}
+
+	var planned []pending
+	targets := map[string]string{}
+
+	err = walkSnapshots(func(name, state, fromPath string) error {
+		raw, err := os.ReadFile(fromPath)
+		if err != nil {
+			return err
+		}
+
+		snap, err := Deserialize(string(raw))
+		if err != nil {
+			var parseErr *ParseError
+			if errors.As(err, &parseErr) {
+				parseErr.File = fromPath
+			}
+			return err
+		}
+		if CompareVersions(snap.Version, FormatVersion) > 0 {
+			return fmt.Errorf("%s: %w %s; upgrade shutter before migrating", fromPath, ErrNewerFormat, snap.Version)
+		}
+
+		fromVersion := snap.Version
+		snap.Version = FormatVersion
+
+		// Snapshots missing the header fields the layout needs keep their name
+		toName := name
+		if snap.Title != "" && ValidateTitle(snap.Title) == nil && (layout != LayoutPerFile || snap.FileName != "") {
+			toName = layoutName(layout, snap)
+		}
+		toPath, err := getSnapshotPath(toName, state)
+		if err != nil {
+			return err
+		}
+
+		data := snap.Serialize()
+		if toPath == fromPath && data == string(raw) {
+			return nil
+		}
+
+		if other, ok := targets[toPath]; ok {
+			return fmt.Errorf("cannot migrate %s: %s would be renamed to the same file", fromPath, other)
+		}
+		targets[toPath] = fromPath
+
+		planned = append(planned, pending{
+			migration: Migration{From: fromPath, To: toPath, FromVersion: fromVersion},
+			fromName:  name,
+			toName:    toName,
+			state:     state,
+			data:      data,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[string]bool{}
+	for _, p := range planned {
+		sources[p.migration.From] = true
+	}
+
+	// Refuse to overwrite files that are not themselves being migrated
+	for _, p := range planned {
+		if p.migration.To == p.migration.From || sources[p.migration.To] {
+			continue
+		}
+		if _, err := os.Stat(p.migration.To); err == nil {
+			return nil, fmt.Errorf("cannot migrate %s: %s already exists", p.migration.From, p.migration.To)
+		}
+	}
+
+	migrations := make([]Migration, len(planned))
+	for i, p := range planned {
+		migrations[i] = p.migration
+	}
+
+	if dryRun {
+		return migrations, nil
+	}
+
+	for _, p := range planned {
+		if err := tx.Write(p.toName, p.state, []byte(p.data)); err != nil {
+			return nil, err
+		}
+		// Files that are also the target of another migration are replaced
+		// by it instead
+		if _, isTarget := targets[p.migration.From]; p.migration.To != p.migration.From && !isTarget {
+			if err := tx.Remove(p.fromName, p.state); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
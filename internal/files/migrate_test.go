@@ -0,0 +1,154 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func writeRawSnapshot(t *testing.T, name, data string) string {
+	t.Helper()
+
+	if err := os.MkdirAll("__snapshots__", 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("__snapshots__", name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrate_RewritesHeaderAndName(t *testing.T) {
+	chdirTemp(t)
+
+	legacy := writeRawSnapshot(t, "my_title.snap",
+		"---\ntitle: My Title\ntest_name: TestMigrate\nfile_path: \nfunc_name: \nversion: 0.1.0\n---\ncontent\n")
+	renamed := writeRawSnapshot(t, "Weird Name.snap.new",
+		"---\ntitle: Sub/Test\ntest_name: TestMigrate\nversion: 0.1.0\n---\npending\n")
+
+	migrations, err := files.Migrate(false)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %+v", migrations)
+	}
+
+	if _, err := os.Stat(renamed); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be renamed", renamed)
+	}
+
+	snap, err := files.ReadNew("sub__test")
+	if err != nil {
+		t.Fatalf("failed to read migrated snapshot: %v", err)
+	}
+	if snap.Version != files.FormatVersion || snap.Content != "pending\n" {
+		t.Errorf("unexpected migrated snapshot: %+v", snap)
+	}
+
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "file_path") || !strings.Contains(string(data), "version: "+files.FormatVersion) {
+		t.Errorf("expected header to be rewritten, got:\n%s", data)
+	}
+
+	// A second run has nothing left to do
+	migrations, err = files.Migrate(false)
+	if err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	if len(migrations) != 0 {
+		t.Errorf("expected no migrations, got %+v", migrations)
+	}
+}
+
+func TestMigrate_DryRunWritesNothing(t *testing.T) {
+	chdirTemp(t)
+
+	const data = "---\ntitle: dry\nversion: 0.1.0\n---\ncontent\n"
+	path := writeRawSnapshot(t, "dry.snap", data)
+
+	migrations, err := files.Migrate(true)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].FromVersion != "0.1.0" {
+		t.Errorf("unexpected migrations: %+v", migrations)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("dry run modified %s:\n%s", path, got)
+	}
+}
+
+func TestMigrate_MalformedFileLeavesDirectoryUntouched(t *testing.T) {
+	chdirTemp(t)
+
+	const good = "---\ntitle: good\nversion: 0.1.0\n---\ncontent\n"
+	goodPath := writeRawSnapshot(t, "good.snap", good)
+	writeRawSnapshot(t, "zz_bad.snap", "---\ntitle: bad\nnot a header\n---\n")
+
+	_, err := files.Migrate(false)
+	var parseErr *files.ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected a ParseError, got %v", err)
+	}
+
+	got, err := os.ReadFile(goodPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != good {
+		t.Errorf("expected %s to be untouched, got:\n%s", goodPath, got)
+	}
+}
+
+func TestMigrate_NewerFormatIsRejected(t *testing.T) {
+	chdirTemp(t)
+
+	writeRawSnapshot(t, "future.snap", "---\ntitle: future\nversion: 9.0.0\n---\ncontent\n")
+
+	_, err := files.Migrate(false)
+	if !errors.Is(err, files.ErrNewerFormat) {
+		t.Errorf("expected ErrNewerFormat, got %v", err)
+	}
+}
+
+func TestMigrate_RenamesAcceptedAndPendingTogether(t *testing.T) {
+	chdirTemp(t)
+
+	accepted := writeRawSnapshot(t, "my:_snap.snap", "---\ntitle: My: Snap\nversion: 0.1.0\n---\naccepted\n")
+	pending := writeRawSnapshot(t, "my:_snap.snap.new", "---\ntitle: My: Snap\nversion: 0.1.0\n---\npending\n")
+
+	if _, err := files.Migrate(false); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	for _, path := range []string{accepted, pending} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be renamed", path)
+		}
+	}
+
+	name := files.SnapshotFileName("My: Snap")
+	for state, want := range map[string]string{"accepted": "accepted\n", "new": "pending\n"} {
+		snap, err := files.ReadSnapshot(name, state)
+		if err != nil {
+			t.Fatalf("failed to read migrated %s snapshot: %v", state, err)
+		}
+		if snap.Content != want {
+			t.Errorf("%s snapshot = %q, want %q", state, snap.Content, want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxFileNameLength is the longest base name (without extension) used
+	// before falling back to a truncated name with a hashed suffix.
+	maxFileNameLength = 120
+	// truncatedFileNameLength is how much of a long name is kept before the
+	// hashed suffix is appended.
+	truncatedFileNameLength = 100
+	// hashSuffixLength is the number of hex digits of the title's hash that
+	// tell apart titles whose escaped names would otherwise be equal.
+	hashSuffixLength = 8
+)
+
+// windowsReservedNames cannot be used as file names on Windows, with or
+// without an extension.
+var windowsReservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// hashSuffix matches names that end like a name with a hashed suffix.
+var hashSuffix = regexp.MustCompile(fmt.Sprintf(`-[0-9a-f]{%d}$`, hashSuffixLength))
+
+// SnapshotFileName returns the base file name (without extension) used to
+// store a snapshot with the given title. The mapping is part of the snapshot
+// format and must stay stable across releases:
+//
+//   - spaces become "_" and path separators ("/" and "\") become "__", so
+//     subtests stay readable
+//   - "%", characters reserved on common file systems (< > : " | ? *) and
+//     control characters are escaped as %xx
+//   - a leading or trailing "." and the first letter of Windows device names
+//     such as "con" are escaped as %xx
+//   - letters are lowercased, so that names never differ only in case
+//
+// Titles that differ only in case or in spaces and underscores share a name,
+// which is reported when both are used in one package. Whenever escaping loses
+// any other information, for example for titles containing "__" or a tab, a
+// hash of the exact title is appended, so such titles never share a file with
+// the title their name reads as. Names longer than 120 bytes are truncated
+// before the hash is appended.
+func SnapshotFileName(snapTitle string) string {
+	name := escapeFileName(snapTitle)
+
+	if len(name) > maxFileNameLength {
+		cut := truncatedFileNameLength
+		for cut > 0 && !utf8.RuneStart(name[cut]) {
+			cut--
+		}
+		return name[:cut] + "-" + titleHash(snapTitle)
+	}
+
+	if foldTitle(unescapeFileName(name)) != foldTitle(snapTitle) || hashSuffix.MatchString(name) {
+		return name + "-" + titleHash(snapTitle)
+	}
+	return name
+}
+
+// escapeFileName returns the readable part of the file name for a title.
+func escapeFileName(snapTitle string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(snapTitle) {
+		switch {
+		case r == '/' || r == '\\':
+			sb.WriteString("__")
+		case unicode.IsSpace(r):
+			sb.WriteByte('_')
+		case r < 0x20 || r == 0x7f || strings.ContainsRune(`%<>:"|?*`, r):
+			fmt.Fprintf(&sb, "%%%02x", r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	name := sb.String()
+	if name == "" {
+		return "_"
+	}
+
+	if strings.HasPrefix(name, ".") {
+		name = "%2e" + name[1:]
+	}
+	if strings.HasSuffix(name, ".") {
+		name = name[:len(name)-1] + "%2e"
+	}
+
+	stem, _, _ := strings.Cut(name, ".")
+	if windowsReservedNames[stem] {
+		name = fmt.Sprintf("%%%02x", name[0]) + name[1:]
+	}
+
+	return name
+}
+
+// unescapeFileName returns the title a name escaped by escapeFileName stands
+// for, assuming nothing was lost: "__" is read as "/" and "_" as a space.
+func unescapeFileName(name string) string {
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch {
+		case strings.HasPrefix(name[i:], "__"):
+			sb.WriteByte('/')
+			i++
+		case name[i] == '_':
+			sb.WriteByte(' ')
+		case name[i] == '%' && i+2 < len(name):
+			b, err := strconv.ParseUint(name[i+1:i+3], 16, 8)
+			if err != nil {
+				sb.WriteByte(name[i])
+				continue
+			}
+			sb.WriteByte(byte(b))
+			i += 2
+		default:
+			sb.WriteByte(name[i])
+		}
+	}
+	return sb.String()
+}
+
+// foldTitle returns title with the differences SnapshotFileName ignores
+// removed: letter case and underscores versus spaces.
+func foldTitle(title string) string {
+	return strings.ReplaceAll(strings.ToLower(title), "_", " ")
+}
+
+// titleHash returns the hashed suffix for a title.
+func titleHash(snapTitle string) string {
+	sum := sha256.Sum256([]byte(snapTitle))
+	return hex.EncodeToString(sum[:])[:hashSuffixLength]
+}
+
+// legacyFileNames returns the names a snapshot with the given title was most
+// likely stored under by earlier naming schemes. Names equal to the current
+// one are omitted.
+func legacyFileNames(snapTitle string) []string {
+	current := SnapshotFileName(snapTitle)
+	var names []string
+	for _, name := range []string{
+		// Before titles were escaped
+		strings.ReplaceAll(strings.ToLower(snapTitle), " ", "_"),
+		// Before distinct titles were told apart by a hash
+		escapeFileName(snapTitle),
+	} {
+		if name != current && !slices.Contains(names, name) && !strings.ContainsAny(name, `/\`) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ValidateTitle reports whether title can be used as a snapshot title.
+// Titles containing ".." path segments are rejected outright rather than
+// escaped, since they almost always indicate a mistake.
+func ValidateTitle(snapTitle string) error {
+	segments := strings.FieldsFunc(snapTitle, func(r rune) bool {
+		return r == '/' || r == '\\'
+	})
+	for _, segment := range segments {
+		if strings.TrimSpace(segment) == ".." {
+			return fmt.Errorf("invalid snapshot title %q: path traversal is not allowed", snapTitle)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package files_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestSnapshotFileName_Escaping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"testuser/admin", "testuser__admin"},
+		{"TestUser/admin", "testuser__admin"},
+		{"User Name_1", "user_name_1"},
+		{"a__b", "a__b-63e5c1c4"},
+		{`windows\path`, "windows__path-5cb2fa5f"},
+		{"time: 12:30", "time%3a_12%3a30"},
+		{`what? "quoted" <tag> a|b *`, "what%3f_%22quoted%22_%3ctag%3e_a%7cb_%2a"},
+		{"100% done", "100%25_done"},
+		{"tab\there", "tab_here-5b876593"},
+		{"bell\x07", "bell%07"},
+		{".hidden", "%2ehidden"},
+		{"trailing.", "trailing%2e"},
+		{"con", "%63on"},
+		{"CON", "%63on"},
+		{"nul.json", "%6eul.json"},
+		{"console", "console"},
+		{"ünïcödé", "ünïcödé"},
+		{"title-0123abcd", "title-0123abcd-f6331e43"},
+		{"", "_-e3b0c442"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := files.SnapshotFileName(tt.input)
+			if result != tt.expected {
+				t.Errorf("SnapshotFileName(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSnapshotFileName_LongTitles(t *testing.T) {
+	long := strings.Repeat("a", 200)
+	other := strings.Repeat("a", 199) + "b"
+
+	name := files.SnapshotFileName(long)
+	if len(name) > 120 {
+		t.Errorf("expected long name to be truncated, got %d bytes", len(name))
+	}
+	if !strings.HasPrefix(name, strings.Repeat("a", 100)+"-") {
+		t.Errorf("expected readable prefix and hashed suffix, got %q", name)
+	}
+	if name == files.SnapshotFileName(other) {
+		t.Error("long titles with a shared prefix must not collide")
+	}
+
+	// Truncation must not split multi-byte characters
+	multiByte := files.SnapshotFileName(strings.Repeat("é", 100))
+	if !strings.HasPrefix(multiByte, strings.Repeat("é", 50)+"-") {
+		t.Errorf("expected truncation on a rune boundary, got %q", multiByte)
+	}
+}
+
+func TestSnapshotFileName_NoCollisions(t *testing.T) {
+	pairs := [][2]string{
+		{"a/b", "a__b"},
+		{"a:", "a%3a"},
+		{"a\tb", "a b"},
+		{`a\b`, "a/b"},
+		{"con", "%63on"},
+		{"Title", files.SnapshotFileName("Title") + "-0123abcd"},
+	}
+
+	for _, pair := range pairs {
+		a, b := files.SnapshotFileName(pair[0]), files.SnapshotFileName(pair[1])
+		if strings.EqualFold(a, b) {
+			t.Errorf("titles %q and %q share the file name %q", pair[0], pair[1], a)
+		}
+	}
+}
+
+func TestValidateTitle(t *testing.T) {
+	valid := []string{"normal", "loading...", "a..b", "TestUser/admin"}
+	for _, title := range valid {
+		if err := files.ValidateTitle(title); err != nil {
+			t.Errorf("ValidateTitle(%q) returned unexpected error: %v", title, err)
+		}
+	}
+
+	invalid := []string{"..", "../../etc/passwd", `..\windows`, "a/../b"}
+	for _, title := range invalid {
+		if err := files.ValidateTitle(title); err == nil {
+			t.Errorf("ValidateTitle(%q) expected error", title)
+		}
+	}
+}
+
+func TestSaveSnapshot_RejectsTraversal(t *testing.T) {
+	chdirTemp(t)
+
+	snap := &files.Snapshot{Title: "../escape", Content: "content"}
+	if err := files.SaveSnapshot(snap, "new"); err == nil {
+		t.Fatal("expected error for traversal title")
+	}
+
+	if _, err := os.Stat("escape.snap.new"); err == nil {
+		t.Error("snapshot must not be written outside the snapshot directory")
+	}
+}
+
+func TestLegacyPath(t *testing.T) {
+	chdirTemp(t)
+
+	title := "Time: 12:30"
+	legacy := &files.Snapshot{Title: title, Content: "legacy content"}
+	legacyPath := writeRawSnapshot(t, "time:_12:30.snap", legacy.Serialize())
+	// Stored under a legacy name of "Other", but for another title
+	writeRawSnapshot(t, "other.snap", (&files.Snapshot{Title: "other"}).Serialize())
+
+	found, ok := files.LegacyPath(legacy)
+	if !ok || found != legacyPath {
+		t.Errorf("LegacyPath() = %q, %v, want %q", found, ok, legacyPath)
+	}
+
+	if found, ok := files.LegacyPath(&files.Snapshot{Title: "Other"}); ok {
+		t.Errorf("expected no legacy file for another title, got %q", found)
+	}
+
+	// Looking for legacy files never renames them
+	if _, err := os.Stat(legacyPath); err != nil {
+		t.Errorf("expected legacy file to be left in place: %v", err)
+	}
+}
@@ -0,0 +1,500 @@
+package files
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// txDirPrefix starts the name of the directories holding in-flight
+// transactions inside the snapshot directory.
+const txDirPrefix = ".shutter-tx-"
+
+// applyRename moves staged files into place while applying a transaction.
+// Tests replace it to make a commit fail halfway through.
+var applyRename = os.Rename
+
+// txAction is a change staged in a transaction.
+type txAction string
+
+const (
+	txAccept txAction = "accept"
+	txReject txAction = "reject"
+	txDelete txAction = "delete"
+	// txWrite and txRemove change a single state of a snapshot.
+	txWrite  txAction = "write"
+	txRemove txAction = "remove"
+)
+
+// txState records how far a transaction got, so that recovery knows whether
+// to roll it back.
+type txState string
+
+const (
+	txPrepared  txState = "prepared"
+	txApplying  txState = "applying"
+	txCommitted txState = "committed"
+)
+
+type txOp struct {
+	Action txAction `json:"action"`
+	Name   string   `json:"name"`
+	// State is the only state changed by the op, or empty if it may change
+	// both the accepted and the pending file.
+	State string `json:"state,omitempty"`
+	// HadAccepted records whether the accepted file existed before the
+	// transaction, so that rolling back an accept can remove it again.
+	HadAccepted bool `json:"had_accepted"`
+	HadNew      bool `json:"had_new"`
+}
+
+type txJournal struct {
+	State txState `json:"state"`
+	Ops   []txOp  `json:"ops"`
+}
+
+// Tx groups operations on snapshot files so that they are applied all
+// together or not at all. Operations are only staged until Commit.
+//
+// Commit first copies every file it will replace or remove into a backup
+// directory and writes a journal, then applies the changes with atomic
+// renames. If applying fails, the backups are restored. If the process dies
+// halfway, the next transaction (or Recover) rolls the changes back.
+type Tx struct {
+	ops []txOp
+	// data holds the contents written by txWrite ops, by op index.
+	data map[int][]byte
+	done bool
+}
+
+// Begin starts a transaction, first recovering any transaction left behind
+// by a crashed process.
+func Begin() (*Tx, error) {
+	if err := Recover(); err != nil {
+		return nil, err
+	}
+	return &Tx{data: map[int][]byte{}}, nil
+}
+
+// Accept stages accepting the pending snapshot stored under name.
+func (tx *Tx) Accept(name string) error {
+	return tx.stage(txAccept, name, "")
+}
+
+// Reject stages rejecting the pending snapshot stored under name.
+func (tx *Tx) Reject(name string) error {
+	return tx.stage(txReject, name, "")
+}
+
+// Delete stages removing both the accepted and pending files of a snapshot.
+func (tx *Tx) Delete(name string) error {
+	return tx.stage(txDelete, name, "")
+}
+
+// Write stages replacing the file of the snapshot stored under name in the
+// given state ("accepted" or "new") with data.
+func (tx *Tx) Write(name, state string, data []byte) error {
+	if err := tx.stage(txWrite, name, state); err != nil {
+		return err
+	}
+	tx.data[len(tx.ops)-1] = data
+	return nil
+}
+
+// Remove stages removing the file of the snapshot stored under name in the
+// given state. A missing file is not an error.
+func (tx *Tx) Remove(name, state string) error {
+	return tx.stage(txRemove, name, state)
+}
+
+func (tx *Tx) stage(action txAction, name, state string) error {
+	if tx.done {
+		return errors.New("transaction already finished")
+	}
+	if err := ValidateTitle(name); err != nil {
+		return err
+	}
+
+	op := txOp{Action: action, Name: name, State: state}
+	for _, other := range tx.ops {
+		if other.Name == name && overlaps(other.states(), op.states()) {
+			return fmt.Errorf("snapshot %q is already part of this transaction", name)
+		}
+	}
+
+	tx.ops = append(tx.ops, op)
+	return nil
+}
+
+// states returns the states of the files op may change.
+func (op txOp) states() []string {
+	if op.State != "" {
+		return []string{op.State}
+	}
+	return []string{"accepted", "new"}
+}
+
+// overlaps reports whether a and b share a state.
+func overlaps(a, b []string) bool {
+	for _, state := range a {
+		if slices.Contains(b, state) {
+			return true
+		}
+	}
+	return false
+}
+
+// AcceptSnapshots accepts the pending snapshots stored under names in a
+// single transaction.
+func AcceptSnapshots(names []string) error {
+	return commitAll(names, (*Tx).Accept)
+}
+
+// RejectSnapshots rejects the pending snapshots stored under names in a
+// single transaction.
+func RejectSnapshots(names []string) error {
+	return commitAll(names, (*Tx).Reject)
+}
+
+// DeleteSnapshots deletes the snapshots stored under names in a single
+// transaction.
+func DeleteSnapshots(names []string) error {
+	return commitAll(names, (*Tx).Delete)
+}
+
+// commitAll stages op for every name and commits the transaction.
+func commitAll(names []string, op func(*Tx, string) error) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	tx, err := Begin()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := op(tx, name); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Len returns the number of staged operations.
+func (tx *Tx) Len() int {
+	return len(tx.ops)
+}
+
+// Rollback discards all staged operations. Nothing has been written yet, so
+// this never fails; it exists so callers can defer it.
+func (tx *Tx) Rollback() error {
+	tx.done = true
+	tx.ops = nil
+	return nil
+}
+
+// Commit applies all staged operations. On error, the snapshot directory is
+// left as it was before Commit.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return errors.New("transaction already finished")
+	}
+	tx.done = true
+	if len(tx.ops) == 0 {
+		return nil
+	}
+
+	if err := tx.commitLocked(); err != nil {
+		return err
+	}
+
+	// Only after the locks are released, since lock files keep directories
+	// from being empty
+	for _, op := range tx.ops {
+		if op.Action == txDelete || op.Action == txRemove {
+			if err := removeEmptyDirs(op.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commitLocked applies the staged operations while holding their locks.
+func (tx *Tx) commitLocked() error {
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
+		return err
+	}
+
+	// Lock in a stable order so concurrent transactions cannot deadlock
+	names := make([]string, len(tx.ops))
+	for i, op := range tx.ops {
+		names[i] = op.Name
+	}
+	sort.Strings(names)
+	for _, name := range slices.Compact(names) {
+		unlock, err := lockSnapshot(name)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	txDir, err := os.MkdirTemp(snapshotDir, txDirPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	unlockTx, err := lockPath(txDir)
+	if err != nil {
+		os.RemoveAll(txDir)
+		return err
+	}
+	defer unlockTx()
+
+	if err := tx.prepare(txDir); err != nil {
+		os.RemoveAll(txDir)
+		return err
+	}
+
+	if err := writeJournal(txDir, txApplying, tx.ops); err != nil {
+		os.RemoveAll(txDir)
+		return err
+	}
+
+	if err := tx.apply(txDir); err != nil {
+		if rollbackErr := rollback(txDir, tx.ops); rollbackErr != nil {
+			return fmt.Errorf("%w (rollback failed, backups kept in %s: %v)", err, txDir, rollbackErr)
+		}
+		os.RemoveAll(txDir)
+		return err
+	}
+
+	if err := writeJournal(txDir, txCommitted, tx.ops); err != nil {
+		return err
+	}
+	return os.RemoveAll(txDir)
+}
+
+// backupPath returns the path of the backup of the file in state for op i.
+func backupPath(txDir string, i int, state string) string {
+	return filepath.Join(txDir, strconv.Itoa(i)+"."+state+".bak")
+}
+
+// stagedPath returns the path of the staged accepted file for op i.
+func stagedPath(txDir string, i int) string {
+	return filepath.Join(txDir, strconv.Itoa(i)+".staged")
+}
+
+// prepare backs up every file the transaction will change and stages the
+// contents of accepted and written snapshots. It changes nothing outside txDir.
+func (tx *Tx) prepare(txDir string) error {
+	for i := range tx.ops {
+		op := &tx.ops[i]
+
+		if op.Action == txWrite {
+			if err := writeFileAtomic(stagedPath(txDir, i), tx.data[i]); err != nil {
+				return err
+			}
+		}
+
+		for _, state := range op.states() {
+			filePath, err := getSnapshotPath(op.Name, state)
+			if err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(filePath)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			if state == "accepted" {
+				op.HadAccepted = true
+			} else {
+				op.HadNew = true
+			}
+			if err := writeFileAtomic(backupPath(txDir, i, state), data); err != nil {
+				return err
+			}
+			if op.Action == txAccept && state == "new" {
+				if err := writeFileAtomic(stagedPath(txDir, i), data); err != nil {
+					return err
+				}
+			}
+		}
+
+		if (op.Action == txAccept || op.Action == txReject) && !op.HadNew {
+			return fmt.Errorf("no pending snapshot %q: %w", op.Name, os.ErrNotExist)
+		}
+	}
+
+	return writeJournal(txDir, txPrepared, tx.ops)
+}
+
+// apply performs the staged operations.
+func (tx *Tx) apply(txDir string) error {
+	for i, op := range tx.ops {
+		acceptedPath, err := getSnapshotPath(op.Name, "accepted")
+		if err != nil {
+			return err
+		}
+		newPath, err := getSnapshotPath(op.Name, "new")
+		if err != nil {
+			return err
+		}
+
+		switch op.Action {
+		case txAccept:
+			if err := applyRename(stagedPath(txDir, i), acceptedPath); err != nil {
+				return err
+			}
+			if err := os.Remove(newPath); err != nil {
+				return err
+			}
+		case txReject:
+			if err := os.Remove(newPath); err != nil {
+				return err
+			}
+		case txDelete:
+			for _, filePath := range []string{acceptedPath, newPath} {
+				if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+		case txWrite:
+			filePath, err := getSnapshotPath(op.Name, op.State)
+			if err != nil {
+				return err
+			}
+			if err := ensureDir(filepath.Dir(filePath)); err != nil {
+				return err
+			}
+			if err := applyRename(stagedPath(txDir, i), filePath); err != nil {
+				return err
+			}
+		case txRemove:
+			filePath, err := getSnapshotPath(op.Name, op.State)
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// rollback restores the snapshot files of ops from the backups in txDir.
+func rollback(txDir string, ops []txOp) error {
+	var errs []error
+	for i, op := range ops {
+		for _, state := range op.states() {
+			filePath, err := getSnapshotPath(op.Name, state)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+
+			had := op.HadAccepted
+			if state == "new" {
+				had = op.HadNew
+			}
+
+			if had {
+				if err := ensureDir(filepath.Dir(filePath)); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if err := os.Rename(backupPath(txDir, i, state), filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, err)
+				}
+			} else if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func writeJournal(txDir string, state txState, ops []txOp) error {
+	data, err := json.MarshalIndent(txJournal{State: state, Ops: ops}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(txDir, "journal.json"), data)
+}
+
+// Recover finishes transactions left behind by a process that crashed during
+// Commit: partially applied ones are rolled back and all others are cleaned up.
+func Recover() error {
+	snapshotDir, err := SnapshotDir()
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(snapshotDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), txDirPrefix) {
+			continue
+		}
+		if err := recoverTx(filepath.Join(snapshotDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func recoverTx(txDir string) error {
+	// Wait for a transaction that is still running in another process
+	unlock, err := lockPath(txDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(filepath.Join(txDir, "journal.json"))
+	if errors.Is(err, os.ErrNotExist) {
+		// Crashed while preparing, or already finished: nothing was applied
+		return os.RemoveAll(txDir)
+	}
+	if err != nil {
+		return err
+	}
+
+	var journal txJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return fmt.Errorf("%s: %w", txDir, err)
+	}
+
+	// A transaction that never started applying changed nothing
+	if journal.State == txApplying {
+		if err := rollback(txDir, journal.Ops); err != nil {
+			return fmt.Errorf("failed to roll back interrupted transaction in %s: %w", txDir, err)
+		}
+	}
+
+	return os.RemoveAll(txDir)
+}
@@ -0,0 +1,247 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func saveSnap(t *testing.T, title, content, state string) {
+	t.Helper()
+	snap := &files.Snapshot{Title: title, Test: "TestTx", Content: content}
+	if err := files.SaveSnapshot(snap, state); err != nil {
+		t.Fatalf("failed to save %s snapshot %q: %v", state, title, err)
+	}
+}
+
+func readContent(t *testing.T, name, state string) string {
+	t.Helper()
+	snap, err := files.ReadSnapshot(name, state)
+	if errors.Is(err, os.ErrNotExist) {
+		return "<missing>"
+	}
+	if err != nil {
+		t.Fatalf("failed to read %s snapshot %q: %v", state, name, err)
+	}
+	return snap.Content
+}
+
+func TestTx_CommitAppliesAllOperations(t *testing.T) {
+	chdirTemp(t)
+
+	saveSnap(t, "first", "old first", "accepted")
+	saveSnap(t, "first", "new first", "new")
+	saveSnap(t, "second", "new second", "new")
+	saveSnap(t, "third", "old third", "accepted")
+
+	tx, err := files.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stage := range []error{tx.Accept("first"), tx.Reject("second"), tx.Delete("third")} {
+		if stage != nil {
+			t.Fatal(stage)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	checks := []struct{ name, state, want string }{
+		{"first", "accepted", "new first"},
+		{"first", "new", "<missing>"},
+		{"second", "new", "<missing>"},
+		{"second", "accepted", "<missing>"},
+		{"third", "accepted", "<missing>"},
+	}
+	for _, c := range checks {
+		if got := readContent(t, c.name, c.state); got != c.want {
+			t.Errorf("%s (%s): got %q, want %q", c.name, c.state, got, c.want)
+		}
+	}
+
+	entries, err := os.ReadDir("__snapshots__")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			t.Errorf("transaction directory %s was not removed", entry.Name())
+		}
+	}
+}
+
+func TestAcceptSnapshots_FailureChangesNothing(t *testing.T) {
+	chdirTemp(t)
+
+	saveSnap(t, "first", "old first", "accepted")
+	saveSnap(t, "first", "new first", "new")
+	saveSnap(t, "second", "old second", "accepted")
+
+	// "second" has no pending snapshot, so the whole batch must fail
+	err := files.AcceptSnapshots([]string{"first", "second"})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a not-exist error, got %v", err)
+	}
+
+	if got := readContent(t, "first", "accepted"); got != "old first" {
+		t.Errorf("accepted snapshot changed to %q", got)
+	}
+	if got := readContent(t, "first", "new"); got != "new first" {
+		t.Errorf("pending snapshot changed to %q", got)
+	}
+}
+
+func TestAcceptSnapshots_ApplyFailureRestoresSnapshots(t *testing.T) {
+	chdirTemp(t)
+
+	saveSnap(t, "first", "old first", "accepted")
+	saveSnap(t, "first", "new first", "new")
+	saveSnap(t, "second", "old second", "accepted")
+	saveSnap(t, "second", "new second", "new")
+
+	// The first snapshot is already accepted when the second rename fails
+	errRename := errors.New("disk full")
+	files.FailNthRename(t, 2, errRename)
+
+	err := files.AcceptSnapshots([]string{"first", "second"})
+	if !errors.Is(err, errRename) {
+		t.Fatalf("expected the rename error, got %v", err)
+	}
+
+	for _, name := range []string{"first", "second"} {
+		if got := readContent(t, name, "accepted"); got != "old "+name {
+			t.Errorf("accepted snapshot %q = %q, want it restored", name, got)
+		}
+		if got := readContent(t, name, "new"); got != "new "+name {
+			t.Errorf("pending snapshot %q = %q, want it restored", name, got)
+		}
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join("__snapshots__", ".shutter-tx-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("expected transaction directory to be removed, got %v", leftovers)
+	}
+}
+
+func TestTx_StageTwiceFails(t *testing.T) {
+	chdirTemp(t)
+
+	tx, err := files.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Accept("same"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Reject("same"); err == nil {
+		t.Error("expected staging the same snapshot twice to fail")
+	}
+}
+
+func TestTx_FinishedTransactionCannotBeReused(t *testing.T) {
+	chdirTemp(t)
+
+	tx, err := files.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Accept("late"); err == nil {
+		t.Error("expected staging after Rollback to fail")
+	}
+	if err := tx.Commit(); err == nil {
+		t.Error("expected Commit after Rollback to fail")
+	}
+}
+
+// writeCrashedTx leaves behind the journal and backups of an accept of
+// "crashed" that was interrupted after replacing the accepted snapshot.
+func writeCrashedTx(t *testing.T, state string) {
+	t.Helper()
+
+	saveSnap(t, "crashed", "old", "accepted")
+	saveSnap(t, "crashed", "new", "new")
+	oldData, err := os.ReadFile(filepath.Join("__snapshots__", "crashed.snap"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newData, err := os.ReadFile(filepath.Join("__snapshots__", "crashed.snap.new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txDir := filepath.Join("__snapshots__", ".shutter-tx-123")
+	if err := os.Mkdir(txDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	backups := map[string][]byte{
+		"0.accepted.bak": oldData,
+		"0.new.bak":      newData,
+		"journal.json": []byte(`{"state": "` + state + `", "ops": [` +
+			`{"action": "accept", "name": "crashed", "had_accepted": true, "had_new": true}]}`),
+	}
+	for name, data := range backups {
+		if err := os.WriteFile(filepath.Join(txDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The accept was applied, but the pending file not yet removed
+	if err := os.WriteFile(filepath.Join("__snapshots__", "crashed.snap"), newData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join("__snapshots__", "crashed.snap.new")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecover_RollsBackInterruptedCommit(t *testing.T) {
+	chdirTemp(t)
+	writeCrashedTx(t, "applying")
+
+	// Interrupted transaction directories are not snapshots
+	pending, err := files.ListNewSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("expected no pending snapshots before recovery, got %v", pending)
+	}
+
+	if err := files.Recover(); err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	if got := readContent(t, "crashed", "accepted"); got != "old" {
+		t.Errorf("accepted snapshot: got %q, want %q", got, "old")
+	}
+	if got := readContent(t, "crashed", "new"); got != "new" {
+		t.Errorf("pending snapshot: got %q, want %q", got, "new")
+	}
+	if _, err := os.Stat(filepath.Join("__snapshots__", ".shutter-tx-123")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected transaction directory to be removed, got %v", err)
+	}
+}
+
+func TestRecover_KeepsCommittedTransaction(t *testing.T) {
+	chdirTemp(t)
+	writeCrashedTx(t, "committed")
+
+	// Begin recovers before starting a new transaction
+	if _, err := files.Begin(); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+
+	if got := readContent(t, "crashed", "accepted"); got != "new" {
+		t.Errorf("accepted snapshot: got %q, want %q", got, "new")
+	}
+	if _, err := os.Stat(filepath.Join("__snapshots__", ".shutter-tx-123")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected transaction directory to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,280 @@
+package files
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoVisitedRecord is returned when no test run has recorded which
+// snapshots it visited for the current snapshot directory.
+var ErrNoVisitedRecord = errors.New("no recorded test run found for this snapshot directory; run 'go test' first")
+
+// ErrIncompleteRun is returned when the most recent test run of a package
+// using the snapshot directory did not finish, failed, or only ran some of
+// its tests, so its record cannot tell which snapshots are obsolete.
+var ErrIncompleteRun = errors.New("the most recent test run did not complete")
+
+var (
+	visitedMu sync.Mutex
+	// visitedSeen holds the titles already written to each record file by
+	// this process. A record file is truncated the first time it is seen.
+	visitedSeen = map[string]map[string]bool{}
+)
+
+// visitedRecordDir returns the directory holding the records of which
+// snapshots were visited by the most recent test run of each package using
+// the snapshot directory. It lives outside the snapshot directory so it
+// never shows up in version control.
+func visitedRecordDir() (string, error) {
+	snapshotDir, err := SnapshotDir()
+	if err != nil {
+		return "", err
+	}
+
+	absDir, err := filepath.Abs(snapshotDir)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(os.TempDir(), "shutter", shortHash(absDir)), nil
+}
+
+// visitedRecordPath returns the path of the record of the current package,
+// identified by the working directory 'go test' runs it in.
+func visitedRecordPath() (string, error) {
+	recordDir, err := visitedRecordDir()
+	if err != nil {
+		return "", err
+	}
+
+	pkgDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(recordDir, shortHash(pkgDir)+".visited"), nil
+}
+
+// completeMarkerPath returns the path of the file marking the record at
+// recordPath as written by a complete test run.
+func completeMarkerPath(recordPath string) string {
+	return strings.TrimSuffix(recordPath, ".visited") + ".complete"
+}
+
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RecordVisited records that the snapshot stored under name was used by the
+// current test run. The first call in a process discards the record left
+// behind by any previous run of the package, and with it the mark that the
+// run was complete.
+func RecordVisited(name string) error {
+	recordPath, err := visitedRecordPath()
+	if err != nil {
+		return err
+	}
+
+	visitedMu.Lock()
+	defer visitedMu.Unlock()
+
+	seen, ok := visitedSeen[recordPath]
+	if ok && seen[name] {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(recordPath), 0755); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	if !ok {
+		if err := os.Remove(completeMarkerPath(recordPath)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(recordPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if !ok {
+		// The package directory, so that errors can name it
+		pkgDir, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(f, "#"+strconv.Quote(pkgDir)); err != nil {
+			return err
+		}
+		seen = map[string]bool{}
+		visitedSeen[recordPath] = seen
+	}
+
+	if _, err := fmt.Fprintln(f, strconv.Quote(name)); err != nil {
+		return err
+	}
+
+	seen[name] = true
+	return nil
+}
+
+// CompleteVisited marks the records written by this process as complete. It
+// must only be called once every test of the package has run and passed,
+// since snapshots of tests that did not reach them would otherwise be
+// considered obsolete.
+func CompleteVisited() error {
+	visitedMu.Lock()
+	defer visitedMu.Unlock()
+
+	var errs []error
+	for recordPath := range visitedSeen {
+		if err := os.WriteFile(completeMarkerPath(recordPath), nil, 0644); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ResetVisited forgets which records this process has written to, so that
+// the next RecordVisited call starts a new run.
+func ResetVisited() {
+	visitedMu.Lock()
+	defer visitedMu.Unlock()
+	visitedSeen = map[string]map[string]bool{}
+}
+
+// ReadVisited returns the names recorded by the most recent test run of
+// every package using the snapshot directory. It fails with ErrIncompleteRun
+// unless all of those runs completed; records of packages whose directory no
+// longer exists are ignored.
+func ReadVisited() ([]string, error) {
+	recordDir, err := visitedRecordDir()
+	if err != nil {
+		return nil, err
+	}
+
+	recordPaths, err := filepath.Glob(filepath.Join(recordDir, "*.visited"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	found := false
+	for _, recordPath := range recordPaths {
+		pkgDir, recorded, err := readRecord(recordPath)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(pkgDir); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if _, err := os.Stat(completeMarkerPath(recordPath)); err != nil {
+			return nil, fmt.Errorf("%w in %s; run all of its tests with shutter.Main in TestMain first", ErrIncompleteRun, pkgDir)
+		}
+		found = true
+		names = append(names, recorded...)
+	}
+
+	if !found {
+		return nil, ErrNoVisitedRecord
+	}
+	return names, nil
+}
+
+// readRecord reads a record written by RecordVisited.
+func readRecord(recordPath string) (pkgDir string, names []string, err error) {
+	f, err := os.Open(recordPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := scanner.Text()
+		if lineNum == 1 {
+			pkgDir, err = strconv.Unquote(strings.TrimPrefix(line, "#"))
+		} else {
+			var name string
+			name, err = strconv.Unquote(line)
+			names = append(names, name)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("%s:%d: invalid record: %w", recordPath, lineNum, err)
+		}
+	}
+
+	return pkgDir, names, scanner.Err()
+}
+
+// ListObsoleteSnapshots returns the names of snapshots in the snapshot
+// directory that were not visited by the most recent test run.
+func ListObsoleteSnapshots() ([]string, error) {
+	visited, err := ReadVisited()
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(visited))
+	for _, name := range visited {
+		used[name] = true
+	}
+
+	obsolete := map[string]bool{}
+	err = walkSnapshots(func(name, _, _ string) error {
+		if !used[name] {
+			obsolete[name] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(obsolete))
+	for name := range obsolete {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// DeleteSnapshot removes both the accepted and pending files for a snapshot,
+// along with any directories left empty. Missing files are not an error.
+func DeleteSnapshot(name string) error {
+	return DeleteSnapshots([]string{name})
+}
+
+// removeEmptyDirs removes the directories of a nested snapshot name that no
+// longer contain any files, stopping at the snapshot directory.
+func removeEmptyDirs(name string) error {
+	snapshotDir, err := getSnapshotDir()
+	if err != nil {
+		return err
+	}
+
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		if os.Remove(filepath.Join(snapshotDir, filepath.FromSlash(dir))) != nil {
+			// Not empty, or already gone
+			return nil
+		}
+	}
+
+	return nil
+}
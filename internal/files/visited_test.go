@@ -0,0 +1,197 @@
+package files_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// chdirTemp switches into a fresh temporary directory for the duration of the
+// test. Records of visited snapshots are kept inside it as well.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	t.Setenv("TMPDIR", filepath.Join(tmpDir, "tmp"))
+
+	return tmpDir
+}
+
+func TestReadVisited_NoRecord(t *testing.T) {
+	chdirTemp(t)
+
+	_, err := files.ReadVisited()
+	if !errors.Is(err, files.ErrNoVisitedRecord) {
+		t.Errorf("expected ErrNoVisitedRecord, got %v", err)
+	}
+}
+
+func TestRecordVisited_RoundTrip(t *testing.T) {
+	chdirTemp(t)
+	files.ResetVisited()
+
+	titles := []string{"First Title", "second", "title with\nnewline", "second"}
+	for _, title := range titles {
+		if err := files.RecordVisited(title); err != nil {
+			t.Fatalf("RecordVisited failed: %v", err)
+		}
+	}
+
+	completeVisited(t)
+
+	visited, err := files.ReadVisited()
+	if err != nil {
+		t.Fatalf("ReadVisited failed: %v", err)
+	}
+
+	expected := []string{"First Title", "second", "title with\nnewline"}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("ReadVisited() = %q, want %q", visited, expected)
+	}
+}
+
+func TestRecordVisited_NewRunTruncates(t *testing.T) {
+	chdirTemp(t)
+	files.ResetVisited()
+
+	if err := files.RecordVisited("old run"); err != nil {
+		t.Fatalf("RecordVisited failed: %v", err)
+	}
+
+	completeVisited(t)
+
+	files.ResetVisited()
+	if err := files.RecordVisited("new run"); err != nil {
+		t.Fatalf("RecordVisited failed: %v", err)
+	}
+	completeVisited(t)
+
+	visited, err := files.ReadVisited()
+	if err != nil {
+		t.Fatalf("ReadVisited failed: %v", err)
+	}
+	if !slices.Equal(visited, []string{"new run"}) {
+		t.Errorf("expected only the latest run to be recorded, got %q", visited)
+	}
+}
+
+func TestReadVisited_IncompleteRun(t *testing.T) {
+	chdirTemp(t)
+	files.ResetVisited()
+
+	if err := files.RecordVisited("first run"); err != nil {
+		t.Fatalf("RecordVisited failed: %v", err)
+	}
+	completeVisited(t)
+
+	// A new run discards the mark of the previous one until it completes
+	files.ResetVisited()
+	if err := files.RecordVisited("crashed run"); err != nil {
+		t.Fatalf("RecordVisited failed: %v", err)
+	}
+
+	_, err := files.ReadVisited()
+	if !errors.Is(err, files.ErrIncompleteRun) {
+		t.Fatalf("expected ErrIncompleteRun, got %v", err)
+	}
+
+	if _, err := files.ListObsoleteSnapshots(); !errors.Is(err, files.ErrIncompleteRun) {
+		t.Errorf("expected ListObsoleteSnapshots to refuse an incomplete run, got %v", err)
+	}
+}
+
+func TestReadVisited_SharedSnapshotDir(t *testing.T) {
+	root := chdirTemp(t)
+	files.SetSnapshotDir(filepath.Join(root, "shared"))
+	t.Cleanup(files.ResetConfig)
+
+	// Each package runs in its own directory and process
+	for _, pkg := range []string{"pkga", "pkgb"} {
+		pkgDir := filepath.Join(root, pkg)
+		if err := os.Mkdir(pkgDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Chdir(pkgDir)
+		files.ResetVisited()
+
+		if err := files.RecordVisited(pkg + "_snapshot"); err != nil {
+			t.Fatalf("RecordVisited failed: %v", err)
+		}
+		completeVisited(t)
+	}
+
+	visited, err := files.ReadVisited()
+	if err != nil {
+		t.Fatalf("ReadVisited failed: %v", err)
+	}
+
+	slices.Sort(visited)
+	expected := []string{"pkga_snapshot", "pkgb_snapshot"}
+	if !slices.Equal(visited, expected) {
+		t.Errorf("ReadVisited() = %q, want the snapshots of both packages %q", visited, expected)
+	}
+}
+
+func TestListObsoleteSnapshots(t *testing.T) {
+	chdirTemp(t)
+	files.ResetVisited()
+
+	for _, snap := range []struct {
+		title string
+		state string
+	}{
+		{"kept snapshot", "accepted"},
+		{"stale snapshot", "accepted"},
+		{"stale pending", "new"},
+	} {
+		s := &files.Snapshot{Title: snap.title, Content: "content"}
+		if err := files.SaveSnapshot(s, snap.state); err != nil {
+			t.Fatalf("SaveSnapshot failed: %v", err)
+		}
+	}
+
+	if err := files.RecordVisited(files.SnapshotFileName("kept snapshot")); err != nil {
+		t.Fatalf("RecordVisited failed: %v", err)
+	}
+	completeVisited(t)
+
+	obsolete, err := files.ListObsoleteSnapshots()
+	if err != nil {
+		t.Fatalf("ListObsoleteSnapshots failed: %v", err)
+	}
+
+	expected := []string{"stale_pending", "stale_snapshot"}
+	if !slices.Equal(obsolete, expected) {
+		t.Fatalf("ListObsoleteSnapshots() = %q, want %q", obsolete, expected)
+	}
+
+	for _, name := range obsolete {
+		if err := files.DeleteSnapshot(name); err != nil {
+			t.Fatalf("DeleteSnapshot(%s) failed: %v", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir("__snapshots__")
+	if err != nil {
+		t.Fatalf("failed to read snapshot dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "kept_snapshot.snap" {
+		t.Errorf("expected only kept_snapshot.snap to remain, got %v", entries)
+	}
+
+	if _, err := os.Stat(filepath.Join("__snapshots__", "kept_snapshot.snap")); err != nil {
+		t.Errorf("expected kept snapshot to survive pruning: %v", err)
+	}
+}
+
+func completeVisited(t *testing.T) {
+	t.Helper()
+	if err := files.CompleteVisited(); err != nil {
+		t.Fatalf("CompleteVisited failed: %v", err)
+	}
+}
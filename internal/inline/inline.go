@@ -0,0 +1,303 @@
+// Package inline rewrites the expected values of inline snapshots in Go
+// source files.
+package inline
+
+import (
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// FuncName is the name of the function whose expected values are rewritten.
+const FuncName = "SnapInline"
+
+// expectedArg is the index of the expected value in a SnapInline call.
+const expectedArg = 2
+
+// shift records how many lines an earlier rewrite added after a line.
+type shift struct {
+	line  int
+	delta int
+}
+
+var (
+	shiftsMu sync.Mutex
+	// shifts tracks rewrites made by this process, keyed by absolute path, so
+	// that line numbers recorded before a rewrite still find their call.
+	shifts = map[string][]shift{}
+)
+
+// Normalize strips leading and trailing blank lines and the indentation
+// common to all lines, so that expected values can be indented to match the
+// surrounding code.
+func Normalize(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	indent, first := "", true
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		lineIndent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			indent, first = lineIndent, false
+			continue
+		}
+		for !strings.HasPrefix(lineIndent, indent) {
+			indent = indent[:len(indent)-1]
+		}
+	}
+
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Literal returns a Go string literal for content. Multi-line content is
+// written as a raw string indented one level deeper than indent.
+func Literal(content, indent string) string {
+	content = Normalize(content)
+
+	rawSafe := !strings.Contains(content, "`")
+	for _, r := range content {
+		if r != '\n' && r != '\t' && !unicode.IsPrint(r) {
+			rawSafe = false
+			break
+		}
+	}
+
+	if !strings.Contains(content, "\n") {
+		if rawSafe && strings.Contains(content, `"`) {
+			return "`" + content + "`"
+		}
+		return strconv.Quote(content)
+	}
+
+	if !rawSafe {
+		return strconv.Quote(content)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("`\n")
+	for line := range strings.SplitSeq(content, "\n") {
+		if line != "" {
+			sb.WriteString(indent + "\t" + line)
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(indent + "`")
+	return sb.String()
+}
+
+// edit replaces src[start:end] with text.
+type edit struct {
+	start, end int
+	text       string
+	line       int // line the update was recorded at
+	delta      int // lines added by the edit
+}
+
+// Rewrite replaces the expected values of the SnapInline calls described by
+// updates with their new values and formats the file. Line numbers refer to
+// the file as it was when the test ran. Each call's current expected value
+// must still match the recorded one, otherwise the source changed since the
+// test run and nothing is written.
+func Rewrite(file string, updates []*files.InlineUpdate) error {
+	absPath, err := filepath.Abs(file)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+
+	src, err := os.ReadFile(absPath)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	parsed, err := parser.ParseFile(fset, absPath, src, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	shiftsMu.Lock()
+	defer shiftsMu.Unlock()
+
+	var edits []edit
+	for _, u := range updates {
+		line := u.Line + lineOffset(absPath, u.Line)
+
+		lit, err := findExpected(fset, parsed, line)
+		if err != nil {
+			return fmt.Errorf("%s: %w", u.Location(), err)
+		}
+
+		current, err := strconv.Unquote(lit.Value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", u.Location(), err)
+		}
+		if Normalize(current) != Normalize(u.Old) {
+			return fmt.Errorf("%s: inline snapshot changed since the test run; rerun the tests", u.Location())
+		}
+
+		start := fset.Position(lit.Pos()).Offset
+		end := fset.Position(lit.End()).Offset
+		text := Literal(u.New, lineIndent(src, start))
+
+		edits = append(edits, edit{
+			start: start,
+			end:   end,
+			text:  text,
+			line:  u.Line,
+			delta: strings.Count(text, "\n") - strings.Count(lit.Value, "\n"),
+		})
+	}
+
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+
+	out := src
+	for i, e := range edits {
+		if i > 0 && e.end > edits[i-1].start {
+			return fmt.Errorf("%s: overlapping inline snapshot updates", file)
+		}
+		out = append(out[:e.start:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+
+	formatted, err := format.Source(out)
+	if err != nil {
+		return fmt.Errorf("%s: failed to format rewritten source: %w", file, err)
+	}
+
+	if err := os.WriteFile(absPath, formatted, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	for _, e := range edits {
+		if e.delta != 0 {
+			shifts[absPath] = append(shifts[absPath], shift{line: e.line, delta: e.delta})
+		}
+	}
+
+	return nil
+}
+
+// Accept rewrites the source files of updates, one file at a time, and drops
+// their pending records. It returns the number of updates applied before any
+// error.
+func Accept(updates []*files.InlineUpdate) (int, error) {
+	byFile := map[string][]*files.InlineUpdate{}
+	var order []string
+	for _, u := range updates {
+		if _, ok := byFile[u.File]; !ok {
+			order = append(order, u.File)
+		}
+		byFile[u.File] = append(byFile[u.File], u)
+	}
+
+	count := 0
+	for _, file := range order {
+		if err := Rewrite(file, byFile[file]); err != nil {
+			return count, err
+		}
+		for _, u := range byFile[file] {
+			if err := files.RemoveInline(u); err != nil {
+				return count, err
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// lineOffset returns how far line has moved due to earlier rewrites of the
+// same file by this process. Callers must hold shiftsMu.
+func lineOffset(absPath string, line int) int {
+	offset := 0
+	for _, s := range shifts[absPath] {
+		if s.line < line {
+			offset += s.delta
+		}
+	}
+	return offset
+}
+
+// findExpected returns the expected-value literal of the innermost SnapInline
+// call spanning line.
+func findExpected(fset *token.FileSet, file *ast.File, line int) (*ast.BasicLit, error) {
+	var found *ast.CallExpr
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isSnapInline(call.Fun) {
+			return true
+		}
+		if fset.Position(call.Pos()).Line <= line && line <= fset.Position(call.End()).Line {
+			found = call
+		}
+		return true
+	})
+
+	if found == nil {
+		return nil, fmt.Errorf("no %s call found", FuncName)
+	}
+	if len(found.Args) <= expectedArg {
+		return nil, fmt.Errorf("%s call has no expected value", FuncName)
+	}
+
+	lit, ok := found.Args[expectedArg].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return nil, fmt.Errorf("expected value of %s must be a string literal", FuncName)
+	}
+	return lit, nil
+}
+
+func isSnapInline(fun ast.Expr) bool {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name == FuncName
+	case *ast.SelectorExpr:
+		return f.Sel.Name == FuncName
+	}
+	return false
+}
+
+// lineIndent returns the leading whitespace of the line containing offset.
+func lineIndent(src []byte, offset int) string {
+	start := offset
+	for start > 0 && src[start-1] != '\n' {
+		start--
+	}
+	end := start
+	for end < len(src) && (src[end] == ' ' || src[end] == '\t') {
+		end++
+	}
+	return string(src[start:end])
+}
@@ -0,0 +1,160 @@
+package inline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+const testSource = `package example
+
+import "testing"
+
+func TestExample(t *testing.T) {
+	shutter.SnapInline(t, "a", "old")
+	shutter.SnapInline(t, value, ` + "`" + `
+		line one
+		line two
+	` + "`" + `)
+	shutter.SnapInline(t, "c", "")
+}
+`
+
+func writeSource(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "example_test.go")
+	if err := os.WriteFile(path, []byte(testSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readSource(t *testing.T, path string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain", "value", "value"},
+		{"trailing newline", "value\n", "value"},
+		{"indented block", "\n\t\tline one\n\t\t  nested\n\t\tline two\n\t", "line one\n  nested\nline two"},
+		{"blank lines inside", "\n    a\n\n    b\n", "a\n\nb"},
+		{"trailing spaces", "a  \nb\t\n", "a\nb"},
+		{"crlf", "a\r\nb\r\n", "a\nb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"simple", "value", `"value"`},
+		{"quotes", `say "hi"`, "`say \"hi\"`"},
+		{"multi-line", "a\n\nb", "`\n\t\ta\n\n\t\tb\n\t`"},
+		{"backtick", "a`b\nc", `"a` + "`" + `b\nc"`},
+		{"control char", "a\x00b", `"a\x00b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Literal(tt.content, "\t"); got != tt.want {
+				t.Errorf("Literal(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite_ReplacesLiterals(t *testing.T) {
+	path := writeSource(t)
+
+	err := Rewrite(path, []*files.InlineUpdate{
+		{File: path, Line: 6, Old: "old", New: "first\nsecond"},
+		{File: path, Line: 8, Old: "line one\nline two", New: "replaced"},
+		{File: path, Line: 11, Old: "", New: `has "quotes"`},
+	})
+	if err != nil {
+		t.Fatalf("Rewrite failed: %v", err)
+	}
+
+	want := `package example
+
+import "testing"
+
+func TestExample(t *testing.T) {
+	shutter.SnapInline(t, "a", ` + "`" + `
+		first
+		second
+	` + "`" + `)
+	shutter.SnapInline(t, value, "replaced")
+	shutter.SnapInline(t, "c", ` + "`has \"quotes\"`" + `)
+}
+`
+	if got := readSource(t, path); got != want {
+		t.Errorf("unexpected source:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRewrite_TracksLineShifts(t *testing.T) {
+	path := writeSource(t)
+
+	// Each update refers to the line reported by the original test run
+	if err := Rewrite(path, []*files.InlineUpdate{{File: path, Line: 6, Old: "old", New: "a\nb\nc"}}); err != nil {
+		t.Fatalf("first Rewrite failed: %v", err)
+	}
+	if err := Rewrite(path, []*files.InlineUpdate{{File: path, Line: 11, Old: "", New: "filled"}}); err != nil {
+		t.Fatalf("second Rewrite failed: %v", err)
+	}
+
+	if got := readSource(t, path); !strings.Contains(got, `shutter.SnapInline(t, "c", "filled")`) {
+		t.Errorf("expected shifted call to be rewritten, got:\n%s", got)
+	}
+}
+
+func TestRewrite_StaleSource(t *testing.T) {
+	path := writeSource(t)
+
+	err := Rewrite(path, []*files.InlineUpdate{{File: path, Line: 6, Old: "something else", New: "new"}})
+	if err == nil || !strings.Contains(err.Error(), "rerun the tests") {
+		t.Fatalf("expected stale source error, got %v", err)
+	}
+	if got := readSource(t, path); got != testSource {
+		t.Errorf("expected source to be untouched, got:\n%s", got)
+	}
+}
+
+func TestRewrite_NonLiteral(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example_test.go")
+	src := "package example\n\nfunc TestExample(t *testing.T) {\n\tshutter.SnapInline(t, \"a\", expected)\n}\n"
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := Rewrite(path, []*files.InlineUpdate{{File: path, Line: 4, New: "new"}})
+	if err == nil || !strings.Contains(err.Error(), "must be a string literal") {
+		t.Errorf("expected non-literal error, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package pretty
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/ptdewey/shutter/internal/diff"
@@ -46,6 +48,13 @@ func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine
 	}
 	sb.WriteString(Blue("  test: ") + newSnapshot.Test + "\n")
 	sb.WriteString(Blue("  file: ") + snapshotFileName + "\n")
+	if newSnapshot.Line > 0 {
+		sb.WriteString(Blue("  source: ") + sourceLocation(newSnapshot) + "\n")
+	}
+	if newSnapshot.Expression != "" {
+		sb.WriteString(Blue("  expression: ") + expressionText(newSnapshot) + "\n")
+	}
+	writeInfo(&sb, newSnapshot)
 	sb.WriteString("\n")
 	// sb.WriteString(Red("  - old snapshot\n"))
 	// sb.WriteString(Green("  + new snapshot\n"))
@@ -74,41 +83,23 @@ func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine
 		strings.Repeat("─", width-(lineNumWidth*2)-1) + "\n"
 	sb.WriteString(topBar)
 
-	for _, dl := range diffLines {
-		var leftNum, rightNum, prefix, formatted string
-
-		// FIX: line number coloring is the same between old and new lines
-		switch dl.Kind {
-		case diff.DiffOld:
-			// For removed lines: show old line number on left, space on right, red -
-			leftNum = Red(fmt.Sprintf("%*d", lineNumWidth, dl.OldNumber))
-			rightNum = strings.Repeat(" ", lineNumWidth)
-			prefix = Red("-")
-			formatted = Red(dl.Line)
-		case diff.DiffNew:
-			// For added lines: space on left, new line number on right, green +
-			leftNum = strings.Repeat(" ", lineNumWidth)
-			rightNum = Green(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
-			prefix = Green("+")
-			formatted = Green(dl.Line)
-		case diff.DiffShared:
-			// For shared lines: show line number centered, │ separator (not gray)
-			leftNum = strings.Repeat(" ", lineNumWidth)
-			rightNum = Gray(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
-			prefix = "│"
-			formatted = dl.Line
+	var sections []diff.Section
+	if newSnapshot.Sections {
+		sections = diff.SplitSections(diffLines)
+	}
+	if sections == nil {
+		for _, dl := range diffLines {
+			writeDiffLine(&sb, dl, lineNumWidth, width)
 		}
-
-		// Adjust for actual display length considering ANSI codes
-		// Account for: 2 spaces padding + 2 line number columns + 2 spaces between + prefix + space
-		maxContentWidth := width - (lineNumWidth * 2) - 8
-		if len(dl.Line) > maxContentWidth {
-			truncated := dl.Line[:maxContentWidth-3] + "..."
-			formatted = formatColoredLine(truncated, dl.Kind)
+	}
+	for _, section := range sections {
+		if section.Changed() {
+			for _, dl := range section.Lines {
+				writeDiffLine(&sb, dl, lineNumWidth, width)
+			}
+			continue
 		}
-
-		display := fmt.Sprintf("%s %s %s %s", leftNum, rightNum, prefix, formatted)
-		sb.WriteString(fmt.Sprintf("  %s\n", display))
+		writeUnchangedSection(&sb, section, lineNumWidth)
 	}
 
 	// Bottom bar with corner (account for both line number columns)
@@ -119,6 +110,62 @@ func DiffSnapshotBox(old, newSnapshot *files.Snapshot, diffLines []diff.DiffLine
 	return sb.String()
 }
 
+// writeDiffLine writes one line of a diff box.
+func writeDiffLine(sb *strings.Builder, dl diff.DiffLine, lineNumWidth, width int) {
+	var leftNum, rightNum, prefix, formatted string
+
+	// FIX: line number coloring is the same between old and new lines
+	switch dl.Kind {
+	case diff.DiffOld:
+		// For removed lines: show old line number on left, space on right, red -
+		leftNum = Red(fmt.Sprintf("%*d", lineNumWidth, dl.OldNumber))
+		rightNum = strings.Repeat(" ", lineNumWidth)
+		prefix = Red("-")
+		formatted = Red(dl.Line)
+	case diff.DiffNew:
+		// For added lines: space on left, new line number on right, green +
+		leftNum = strings.Repeat(" ", lineNumWidth)
+		rightNum = Green(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
+		prefix = Green("+")
+		formatted = Green(dl.Line)
+	case diff.DiffShared:
+		// For shared lines: show line number centered, │ separator (not gray)
+		leftNum = strings.Repeat(" ", lineNumWidth)
+		rightNum = Gray(fmt.Sprintf("%*d", lineNumWidth, dl.NewNumber))
+		prefix = "│"
+		formatted = dl.Line
+	}
+
+	// Adjust for actual display length considering ANSI codes
+	// Account for: 2 spaces padding + 2 line number columns + 2 spaces between + prefix + space
+	maxContentWidth := width - (lineNumWidth * 2) - 8
+	if len(dl.Line) > maxContentWidth {
+		truncated := dl.Line[:maxContentWidth-3] + "..."
+		formatted = formatColoredLine(truncated, dl.Kind)
+	}
+
+	display := fmt.Sprintf("%s %s %s %s", leftNum, rightNum, prefix, formatted)
+	sb.WriteString(fmt.Sprintf("  %s\n", display))
+}
+
+// writeUnchangedSection writes an unchanged section of a diff box collapsed to
+// its header and the number of hidden lines.
+func writeUnchangedSection(sb *strings.Builder, section diff.Section, lineNumWidth int) {
+	first := section.Lines[0]
+	hidden := len(section.Lines) - 1
+	if section.Name == "" {
+		hidden++
+	}
+
+	leftNum := strings.Repeat(" ", lineNumWidth)
+	rightNum := Gray(fmt.Sprintf("%*d", lineNumWidth, first.NewNumber))
+	line := Gray(fmt.Sprintf("... %s unchanged", lineCount(hidden)))
+	if section.Name != "" {
+		line = Bold(first.Line) + " " + line
+	}
+	sb.WriteString(fmt.Sprintf("  %s %s │ %s\n", leftNum, rightNum, line))
+}
+
 func newSnapshotBoxInternal(snap *files.Snapshot) string {
 	width := TerminalWidth()
 
@@ -132,8 +179,12 @@ func newSnapshotBoxInternal(snap *files.Snapshot) string {
 		sb.WriteString(Blue("  test: ") + snap.Test + "\n")
 	}
 	if snap.FileName != "" {
-		sb.WriteString(Blue("  file: ") + snap.FileName + "\n")
+		sb.WriteString(Blue("  file: ") + sourceLocation(snap) + "\n")
+	}
+	if snap.Expression != "" {
+		sb.WriteString(Blue("  expression: ") + expressionText(snap) + "\n")
 	}
+	writeInfo(&sb, snap)
 	sb.WriteString("\n")
 
 	lines := strings.Split(snap.Content, "\n")
@@ -162,3 +213,32 @@ func newSnapshotBoxInternal(snap *files.Snapshot) string {
 
 	return sb.String()
 }
+
+// sourceLocation returns the file and, if known, line that took a snapshot.
+func sourceLocation(snap *files.Snapshot) string {
+	if snap.Line > 0 {
+		return fmt.Sprintf("%s:%d", snap.FileName, snap.Line)
+	}
+	return snap.FileName
+}
+
+// expressionText returns the source expression of a snapshot, indenting any
+// continuation lines under the header.
+func expressionText(snap *files.Snapshot) string {
+	return strings.ReplaceAll(snap.Expression, "\n", "\n    ")
+}
+
+// writeInfo writes the description and metadata of a snapshot, if any.
+func writeInfo(sb *strings.Builder, snap *files.Snapshot) {
+	if snap.Description != "" {
+		sb.WriteString(Blue("  description: ") + snap.Description + "\n")
+	}
+	if len(snap.Metadata) == 0 {
+		return
+	}
+	sb.WriteString(Blue("  metadata:") + "\n")
+	for _, key := range slices.Sorted(maps.Keys(snap.Metadata)) {
+		value := strings.ReplaceAll(snap.Metadata[key], "\n", "\n      ")
+		sb.WriteString(Blue("    "+key+": ") + value + "\n")
+	}
+}
@@ -644,6 +644,98 @@ func TestNewSnapshotBox_Basic(t *testing.T) {
 	}
 }
 
+// TestSnapshotBoxes_Source tests that the source location and expression are shown
+func TestSnapshotBoxes_Source(t *testing.T) {
+	os.Unsetenv("NO_COLOR")
+	os.Setenv("COLUMNS", "100")
+	defer os.Unsetenv("COLUMNS")
+
+	snap := &files.Snapshot{
+		Title:      "User",
+		Test:       "TestUser",
+		FileName:   "user_test.go",
+		Line:       12,
+		Expression: "api.GetUser(\"123\")",
+		Content:    "new",
+	}
+	old := &files.Snapshot{Title: "User", Test: "TestUser", Content: "old"}
+
+	boxes := map[string]string{
+		"new":  stripANSI(pretty.NewSnapshotBox(snap)),
+		"diff": stripANSI(pretty.DiffSnapshotBox(old, snap, diff.Histogram(old.Content, snap.Content))),
+	}
+	for name, box := range boxes {
+		if !strings.Contains(box, "user_test.go:12") {
+			t.Errorf("%s box: expected the source location, got:\n%s", name, box)
+		}
+		if !strings.Contains(box, "expression: api.GetUser(\"123\")") {
+			t.Errorf("%s box: expected the expression, got:\n%s", name, box)
+		}
+	}
+}
+
+// TestSnapshotBoxes_Info tests that the description and metadata are shown
+func TestSnapshotBoxes_Info(t *testing.T) {
+	os.Unsetenv("NO_COLOR")
+	os.Setenv("COLUMNS", "100")
+	defer os.Unsetenv("COLUMNS")
+
+	snap := &files.Snapshot{
+		Title:       "User",
+		Test:        "TestUser",
+		Description: "GET /users/123 as an admin",
+		Metadata:    map[string]string{"role": "admin", "id": "123"},
+		Content:     "new",
+	}
+	old := &files.Snapshot{Title: "User", Test: "TestUser", Content: "old"}
+
+	boxes := map[string]string{
+		"new":  stripANSI(pretty.NewSnapshotBox(snap)),
+		"diff": stripANSI(pretty.DiffSnapshotBox(old, snap, diff.Histogram(old.Content, snap.Content))),
+	}
+	for name, box := range boxes {
+		if !strings.Contains(box, "description: GET /users/123 as an admin") {
+			t.Errorf("%s box: expected the description, got:\n%s", name, box)
+		}
+		if !strings.Contains(box, "metadata:\n    id: 123\n    role: admin\n") {
+			t.Errorf("%s box: expected sorted metadata, got:\n%s", name, box)
+		}
+	}
+}
+
+// TestDiffSnapshotBox_Sections tests that unchanged sections are collapsed
+func TestDiffSnapshotBox_Sections(t *testing.T) {
+	os.Unsetenv("NO_COLOR")
+	os.Setenv("COLUMNS", "100")
+	defer os.Unsetenv("COLUMNS")
+
+	oldContent := "=== request ===\nGET /users\nAccept: json\n=== response ===\n200"
+	newContent := "=== request ===\nGET /users\nAccept: json\n=== response ===\n404"
+	old := &files.Snapshot{Title: "Sections", Test: "TestSections", Content: oldContent, Sections: true}
+	snap := &files.Snapshot{Title: "Sections", Test: "TestSections", Content: newContent, Sections: true}
+
+	result := pretty.DiffSnapshotBox(old, snap, diff.Histogram(oldContent, newContent))
+	stripped := stripANSI(result)
+
+	if !strings.Contains(stripped, "=== request === ... 2 lines unchanged") {
+		t.Errorf("expected the request section to be collapsed, got:\n%s", stripped)
+	}
+	if strings.Contains(stripped, "GET /users") {
+		t.Errorf("expected unchanged lines to be hidden, got:\n%s", stripped)
+	}
+	if !containsDiffLine(result, "-", "200") || !containsDiffLine(result, "+", "404") {
+		t.Errorf("expected the response change, got:\n%s", stripped)
+	}
+
+	// Lines that merely look like section headers in other snapshots are
+	// shown as they are
+	snap.Sections = false
+	stripped = stripANSI(pretty.DiffSnapshotBox(old, snap, diff.Histogram(oldContent, newContent)))
+	if strings.Contains(stripped, "lines unchanged") || !strings.Contains(stripped, "GET /users") {
+		t.Errorf("expected content without sections to be shown in full, got:\n%s", stripped)
+	}
+}
+
 // TestNewSnapshotBox_EmptyContent tests new snapshot with empty content
 func TestNewSnapshotBox_EmptyContent(t *testing.T) {
 	os.Unsetenv("NO_COLOR")
@@ -0,0 +1,180 @@
+package pretty
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+const (
+	// compactContext is the number of unchanged lines shown around each hunk.
+	compactContext = 2
+	// compactMaxLines caps the lines shown for a new snapshot in compact form.
+	compactMaxLines = 10
+)
+
+// CompactDiff renders only the changed hunks of a diff, with a little context
+// around each, in unified diff style.
+func CompactDiff(newSnapshot *files.Snapshot, diffLines []diff.DiffLine) string {
+	var sb strings.Builder
+	sb.WriteString(Bold(snapshotLabel(newSnapshot)) + " " + Gray(changeCounts(diffLines)) + "\n")
+
+	for _, hunk := range hunks(diffLines, compactContext) {
+		oldStart, oldCount, newStart, newCount := hunkRange(hunk)
+		header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount)
+		if newSnapshot.Sections {
+			if name := hunkSection(diffLines, hunk); name != "" {
+				header += " " + name
+			}
+		}
+		sb.WriteString(Blue(header) + "\n")
+
+		for _, dl := range hunk {
+			switch dl.Kind {
+			case diff.DiffOld:
+				sb.WriteString(Red("- "+dl.Line) + "\n")
+			case diff.DiffNew:
+				sb.WriteString(Green("+ "+dl.Line) + "\n")
+			default:
+				sb.WriteString("  " + dl.Line + "\n")
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// CompactNewSnapshot renders the first lines of a new snapshot without a box.
+func CompactNewSnapshot(snap *files.Snapshot) string {
+	lines := strings.Split(snap.Content, "\n")
+
+	var sb strings.Builder
+	sb.WriteString(Bold(snapshotLabel(snap)) + " " + Gray("(new, "+lineCount(len(lines))+")") + "\n")
+
+	for i, line := range lines {
+		if i == compactMaxLines {
+			sb.WriteString(Gray(fmt.Sprintf("  ... %d more lines", len(lines)-compactMaxLines)) + "\n")
+			break
+		}
+		sb.WriteString(Green("+ "+line) + "\n")
+	}
+
+	return sb.String()
+}
+
+// DiffSummary renders a one-line summary of a changed snapshot, naming the
+// changed sections of sectioned content.
+func DiffSummary(newSnapshot *files.Snapshot, diffLines []diff.DiffLine) string {
+	summary := snapshotLabel(newSnapshot) + " " + changeCounts(diffLines)
+
+	var changed []string
+	if newSnapshot.Sections {
+		for _, section := range diff.SplitSections(diffLines) {
+			if section.Name != "" && section.Changed() {
+				changed = append(changed, section.Name)
+			}
+		}
+	}
+	if len(changed) > 0 {
+		summary += " in " + strings.Join(changed, ", ")
+	}
+	return summary
+}
+
+// NewSnapshotSummary renders a one-line summary of a new snapshot.
+func NewSnapshotSummary(snap *files.Snapshot) string {
+	return snapshotLabel(snap) + " (new, " + lineCount(strings.Count(snap.Content, "\n")+1) + ")"
+}
+
+// lineCount formats a number of lines.
+func lineCount(n int) string {
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// snapshotLabel identifies a snapshot by its title, falling back to its test.
+func snapshotLabel(snap *files.Snapshot) string {
+	if snap.Title != "" {
+		return snap.Title
+	}
+	return snap.Test
+}
+
+// changeCounts describes how many lines a diff adds and removes.
+func changeCounts(diffLines []diff.DiffLine) string {
+	added, removed := 0, 0
+	for _, dl := range diffLines {
+		switch dl.Kind {
+		case diff.DiffNew:
+			added++
+		case diff.DiffOld:
+			removed++
+		}
+	}
+	return fmt.Sprintf("(+%d -%d)", added, removed)
+}
+
+// hunks splits diffLines into groups of changes, each surrounded by up to
+// context shared lines. Changes closer than 2*context lines share a hunk.
+func hunks(diffLines []diff.DiffLine, context int) [][]diff.DiffLine {
+	var result [][]diff.DiffLine
+	start, end := -1, -1
+
+	for i, dl := range diffLines {
+		if dl.Kind == diff.DiffShared {
+			continue
+		}
+		lo := max(i-context, 0)
+		if start >= 0 && lo > end {
+			result = append(result, diffLines[start:end])
+			start = -1
+		}
+		if start < 0 {
+			start = lo
+		}
+		end = min(i+context+1, len(diffLines))
+	}
+	if start >= 0 {
+		result = append(result, diffLines[start:end])
+	}
+
+	return result
+}
+
+// hunkSection returns the name of the section containing the first line of
+// hunk, or an empty string if the content has no sections.
+func hunkSection(diffLines, hunk []diff.DiffLine) string {
+	var name string
+	for _, dl := range diffLines {
+		if n, ok := diff.SectionName(dl.Line); ok {
+			name = n
+		}
+		if dl.OldNumber == hunk[0].OldNumber && dl.NewNumber == hunk[0].NewNumber {
+			break
+		}
+	}
+	return name
+}
+
+// hunkRange returns the unified diff line ranges covered by a hunk.
+func hunkRange(hunk []diff.DiffLine) (oldStart, oldCount, newStart, newCount int) {
+	for _, dl := range hunk {
+		if dl.Kind != diff.DiffNew {
+			if oldStart == 0 {
+				oldStart = dl.OldNumber
+			}
+			oldCount++
+		}
+		if dl.Kind != diff.DiffOld {
+			if newStart == 0 {
+				newStart = dl.NewNumber
+			}
+			newCount++
+		}
+	}
+	return oldStart, oldCount, newStart, newCount
+}
@@ -0,0 +1,94 @@
+package pretty_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+func TestCompactDiff_OnlyShowsHunks(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	oldLines := make([]string, 20)
+	for i := range oldLines {
+		oldLines[i] = "line " + string(rune('a'+i))
+	}
+	newLines := append([]string{}, oldLines...)
+	newLines[1] = "changed b"
+	newLines[15] = "changed p"
+
+	oldContent := strings.Join(oldLines, "\n")
+	newContent := strings.Join(newLines, "\n")
+	snap := &files.Snapshot{Title: "compact", Content: newContent}
+
+	got := pretty.CompactDiff(snap, diff.Histogram(oldContent, newContent))
+	want := `compact (+2 -2)
+@@ -1,4 +1,4 @@
+  line a
+- line b
++ changed b
+  line c
+  line d
+@@ -14,5 +14,5 @@
+  line n
+  line o
+- line p
++ changed p
+  line q
+  line r
+`
+	if got != want {
+		t.Errorf("CompactDiff() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCompactNewSnapshot_Truncates(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	snap := &files.Snapshot{Title: "long", Content: strings.Repeat("x\n", 14) + "x"}
+	got := pretty.CompactNewSnapshot(snap)
+
+	if !strings.HasPrefix(got, "long (new, 15 lines)\n") {
+		t.Errorf("unexpected header:\n%s", got)
+	}
+	if strings.Count(got, "+ x") != 10 || !strings.Contains(got, "... 5 more lines") {
+		t.Errorf("expected 10 lines and a truncation note, got:\n%s", got)
+	}
+}
+
+func TestSummaries(t *testing.T) {
+	snap := &files.Snapshot{Test: "TestNoTitle", Content: "a\nb"}
+
+	if got := pretty.NewSnapshotSummary(snap); got != "TestNoTitle (new, 2 lines)" {
+		t.Errorf("NewSnapshotSummary() = %q", got)
+	}
+	if got := pretty.DiffSummary(snap, diff.Histogram("a", "a\nb")); got != "TestNoTitle (+1 -0)" {
+		t.Errorf("DiffSummary() = %q", got)
+	}
+}
+
+func TestCompactDiff_Sections(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+
+	oldContent := "=== request ===\nGET /users\n=== response ===\na\nb\nc\nd\n200"
+	newContent := "=== request ===\nGET /users\n=== response ===\na\nb\nc\nd\n404"
+	snap := &files.Snapshot{Title: "sections", Content: newContent, Sections: true}
+	diffLines := diff.Histogram(oldContent, newContent)
+
+	got := pretty.CompactDiff(snap, diffLines)
+	if !strings.Contains(got, "@@ -6,3 +6,3 @@ response\n") {
+		t.Errorf("expected the hunk header to name its section, got:\n%s", got)
+	}
+
+	if got := pretty.DiffSummary(snap, diffLines); got != "sections (+1 -1) in response" {
+		t.Errorf("DiffSummary() = %q", got)
+	}
+
+	snap.Sections = false
+	if got := pretty.DiffSummary(snap, diffLines); got != "sections (+1 -1)" {
+		t.Errorf("DiffSummary() without sections = %q", got)
+	}
+}
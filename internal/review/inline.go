@@ -0,0 +1,56 @@
+package review
+
+import (
+	"fmt"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/inline"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// showInline prints an inline snapshot update as a diff against the expected
+// value in the source.
+func showInline(u *files.InlineUpdate) error {
+	old, new := u.Snapshots()
+	if u.Old == "" {
+		fmt.Println(pretty.NewSnapshotBox(new))
+		return nil
+	}
+
+	fmt.Println(pretty.DiffSnapshotBox(old, new, diff.Histogram(old.Content, new.Content)))
+	return nil
+}
+
+// acceptInline rewrites the expected value in the source and drops the
+// pending update.
+func acceptInline(u *files.InlineUpdate) error {
+	_, err := inline.Accept([]*files.InlineUpdate{u})
+	return err
+}
+
+// acceptAllInline applies all pending inline updates, one source file at a time.
+func acceptAllInline() (int, error) {
+	updates, err := files.ListInline()
+	if err != nil {
+		return 0, err
+	}
+	return inline.Accept(updates)
+}
+
+// rejectAllInline drops all pending inline updates.
+func rejectAllInline() (int, error) {
+	updates, err := files.ListInline()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, u := range updates {
+		if err := files.RemoveInline(u); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
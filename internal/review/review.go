@@ -2,12 +2,15 @@ package review
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/inline"
 	"github.com/ptdewey/shutter/internal/pretty"
 )
 
@@ -27,71 +30,212 @@ func computeDiffLines(old, new *files.Snapshot) []diff.DiffLine {
 	return diff.Histogram(old.Content, new.Content)
 }
 
-// applyToSnapshots applies an operation to all snapshots and returns the count of successful operations
-func applyToSnapshots(snapshots []string, operation func(string) error) (int, error) {
-	successCount := 0
-	for _, snapTitle := range snapshots {
-		if err := operation(snapTitle); err != nil {
-			return successCount, err
+// formatWarning returns guidance for an accepted snapshot written by an older
+// format version, or an empty string if it is current.
+func formatWarning(snap *files.Snapshot) string {
+	if files.CompareVersions(snap.Version, files.FormatVersion) >= 0 {
+		return ""
+	}
+	version := snap.Version
+	if version == "" {
+		version = "unknown"
+	}
+	return fmt.Sprintf("⚠ Accepted snapshot uses format %s (current %s); run 'shutter migrate' to upgrade",
+		version, files.FormatVersion)
+}
+
+// Item is a pending change offered for review: a .snap.new file stored under
+// Name, or an inline snapshot update.
+type Item struct {
+	Name   string
+	Inline *files.InlineUpdate
+}
+
+// Title returns the snapshot name, or the call site of an inline update.
+func (item Item) Title() string {
+	if item.Inline != nil {
+		return item.Inline.Location()
+	}
+	return item.Name
+}
+
+// Accept applies the pending change.
+func (item Item) Accept() error {
+	if item.Inline != nil {
+		return acceptInline(item.Inline)
+	}
+	return files.AcceptSnapshot(item.Name)
+}
+
+// Reject drops the pending change.
+func (item Item) Reject() error {
+	if item.Inline != nil {
+		return files.RemoveInline(item.Inline)
+	}
+	return files.RejectSnapshot(item.Name)
+}
+
+// show prints the pending change.
+func (item Item) show() error {
+	if item.Inline != nil {
+		return showInline(item.Inline)
+	}
+	return showSnapshot(item.Name)
+}
+
+// PendingItems returns the pending snapshot files followed by the pending
+// inline updates.
+func PendingItems() ([]Item, error) {
+	snapshots, err := files.ListNewSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	updates, err := files.ListInline()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(snapshots)+len(updates))
+	for _, name := range snapshots {
+		items = append(items, Item{Name: name})
+	}
+	for _, u := range updates {
+		items = append(items, Item{Inline: u})
+	}
+	return items, nil
+}
+
+// Apply accepts or rejects items and returns how many were applied. Snapshot
+// files are changed in a single transaction first, so a failure leaves all of
+// them untouched; inline updates live in source files and are applied one
+// file at a time afterwards.
+func Apply(items []Item, accept bool) (int, error) {
+	var names []string
+	var updates []*files.InlineUpdate
+	for _, item := range items {
+		if item.Inline != nil {
+			updates = append(updates, item.Inline)
+		} else {
+			names = append(names, item.Name)
+		}
+	}
+
+	var err error
+	if accept {
+		err = files.AcceptSnapshots(names)
+	} else {
+		err = files.RejectSnapshots(names)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if accept {
+		count, err := inline.Accept(updates)
+		return len(names) + count, err
+	}
+	for i, u := range updates {
+		if err := files.RemoveInline(u); err != nil {
+			return len(names) + i, err
 		}
-		successCount++
 	}
-	return successCount, nil
+	return len(names) + len(updates), nil
 }
 
 func Review() error {
-	snapshots, err := files.ListNewSnapshots()
+	if err := dropRedundant(); err != nil {
+		return err
+	}
+
+	items, err := PendingItems()
 	if err != nil {
 		return err
 	}
 
-	if len(snapshots) == 0 {
+	if len(items) == 0 {
 		fmt.Println(pretty.Success("✓ No new snapshots to review"))
 		return nil
 	}
 
 	fmt.Println(pretty.Header("Review Snapshots"))
-	fmt.Printf("Found %d new snapshot(s) to review\n\n", len(snapshots))
+	fmt.Printf("Found %d new snapshot(s) to review\n\n", len(items))
 
-	return reviewLoop(snapshots)
+	return reviewLoop(items)
 }
 
-func reviewLoop(snapshots []string) error {
-	reader := bufio.NewReader(os.Stdin)
+// dropRedundant rejects pending snapshots identical to their accepted version,
+// such as those left behind by a flaky test that has since passed again.
+func dropRedundant() error {
+	redundant, err := files.ListRedundantSnapshots()
+	if err != nil {
+		return err
+	}
+	if len(redundant) == 0 {
+		return nil
+	}
+
+	if err := files.RejectSnapshots(redundant); err != nil {
+		return err
+	}
 
-	for i, snapTitle := range snapshots {
-		fmt.Printf("\n[%d/%d] %s\n", i+1, len(snapshots), pretty.Header(snapTitle))
+	fmt.Printf(pretty.Gray("⊘ Dropped %d pending snapshot(s) identical to the accepted version\n"), len(redundant))
+	return nil
+}
 
-		newSnap, err := files.ReadSnapshot(snapTitle, "new")
-		if err != nil {
-			fmt.Println(pretty.Error("✗ Failed to read new snapshot: " + err.Error()))
-			continue
+// showSnapshot prints a pending snapshot, diffed against the accepted one if
+// it exists. Errors name the file that could not be read.
+func showSnapshot(snapTitle string) error {
+	newSnap, err := files.ReadSnapshot(snapTitle, "new")
+	if err != nil {
+		return fmt.Errorf("new snapshot: %w", err)
+	}
+
+	accepted, acceptErr := files.ReadSnapshot(snapTitle, "accepted")
+	if acceptErr != nil && !errors.Is(acceptErr, os.ErrNotExist) {
+		return fmt.Errorf("accepted snapshot: %w", acceptErr)
+	}
+	if acceptErr == nil {
+		if warning := formatWarning(accepted); warning != "" {
+			fmt.Println(pretty.Warning(warning))
 		}
+	}
+
+	if acceptErr == nil {
+		diffLines := computeDiffLines(accepted, newSnap)
+		fmt.Println(pretty.DiffSnapshotBox(accepted, newSnap, diffLines))
+	} else {
+		fmt.Println(pretty.NewSnapshotBox(newSnap))
+	}
+	return nil
+}
 
-		accepted, acceptErr := files.ReadSnapshot(snapTitle, "accepted")
+func reviewLoop(items []Item) error {
+	reader := bufio.NewReader(os.Stdin)
 
-		if acceptErr == nil {
-			diffLines := computeDiffLines(accepted, newSnap)
-			fmt.Println(pretty.DiffSnapshotBox(accepted, newSnap, diffLines))
-		} else {
-			fmt.Println(pretty.NewSnapshotBox(newSnap))
+	for i, item := range items {
+		fmt.Printf("\n[%d/%d] %s\n", i+1, len(items), pretty.Header(item.Title()))
+
+		if err := item.show(); err != nil {
+			fmt.Println(pretty.Error("✗ Failed to read " + err.Error()))
+			continue
 		}
 
 		for {
-			choice, err := askChoice(reader, i+1, len(snapshots))
+			choice, err := askChoice(reader, i+1, len(items))
 			if err != nil {
 				return err
 			}
 
 			switch choice {
 			case Accept:
-				if err := files.AcceptSnapshot(snapTitle); err != nil {
+				if err := item.Accept(); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to accept snapshot: " + err.Error()))
 				} else {
 					fmt.Println(pretty.Success("✓ Snapshot accepted"))
 				}
 			case Reject:
-				if err := files.RejectSnapshot(snapTitle); err != nil {
+				if err := item.Reject(); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to reject snapshot: " + err.Error()))
 				} else {
 					fmt.Println(pretty.Warning("⊘ Snapshot rejected"))
@@ -99,23 +243,23 @@ func reviewLoop(snapshots []string) error {
 			case Skip:
 				fmt.Println(pretty.Warning("⊘ Snapshot skipped"))
 			case AcceptAllChoice:
-				remaining := snapshots[i:]
-				if _, err := applyToSnapshots(remaining, files.AcceptSnapshot); err != nil {
+				remaining := items[i:]
+				if _, err := Apply(remaining, true); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to accept snapshot: " + err.Error()))
 					return err
 				}
 				fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), len(remaining))
 				return nil
 			case RejectAllChoice:
-				remaining := snapshots[i:]
-				if _, err := applyToSnapshots(remaining, files.RejectSnapshot); err != nil {
+				remaining := items[i:]
+				if _, err := Apply(remaining, false); err != nil {
 					fmt.Println(pretty.Error("✗ Failed to reject snapshot: " + err.Error()))
 					return err
 				}
 				fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(remaining))
 				return nil
 			case SkipAllChoice:
-				fmt.Printf(pretty.Warning("⊘ Skipped %d snapshot(s)\n"), len(snapshots)-i)
+				fmt.Printf(pretty.Warning("⊘ Skipped %d snapshot(s)\n"), len(items)-i)
 				return nil
 			case Quit:
 				fmt.Println("\nReview interrupted")
@@ -166,12 +310,16 @@ func AcceptAll() error {
 		return err
 	}
 
-	count, err := applyToSnapshots(snapshots, files.AcceptSnapshot)
+	if err := files.AcceptSnapshots(snapshots); err != nil {
+		return err
+	}
+
+	inlineCount, err := acceptAllInline()
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), count)
+	fmt.Printf(pretty.Success("✓ Accepted %d snapshot(s)\n"), len(snapshots)+inlineCount)
 	return nil
 }
 
@@ -181,11 +329,90 @@ func RejectAll() error {
 		return err
 	}
 
-	count, err := applyToSnapshots(snapshots, files.RejectSnapshot)
+	if err := files.RejectSnapshots(snapshots); err != nil {
+		return err
+	}
+
+	inlineCount, err := rejectAllInline()
 	if err != nil {
 		return err
 	}
 
-	fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), count)
+	fmt.Printf(pretty.Warning("⊘ Rejected %d snapshot(s)\n"), len(snapshots)+inlineCount)
 	return nil
 }
+
+// Prune removes snapshots that were not visited by the most recent test run.
+// When dryRun is true, obsolete snapshots are only listed.
+func Prune(dryRun bool) error {
+	obsolete, err := files.ListObsoleteSnapshots()
+	if err != nil {
+		return err
+	}
+
+	if len(obsolete) == 0 {
+		fmt.Println(pretty.Success("✓ No obsolete snapshots found"))
+		return nil
+	}
+
+	for _, name := range obsolete {
+		fmt.Println("  " + pretty.Gray(name+".snap"))
+	}
+
+	if dryRun {
+		fmt.Printf(pretty.Warning("⊘ Found %d obsolete snapshot(s) (dry run, nothing deleted)\n"), len(obsolete))
+		return nil
+	}
+
+	if err := files.DeleteSnapshots(obsolete); err != nil {
+		return err
+	}
+
+	fmt.Printf(pretty.Success("✓ Pruned %d obsolete snapshot(s)\n"), len(obsolete))
+	return nil
+}
+
+// Migrate rewrites all snapshots using the current snapshot format.
+// When dryRun is true, the files that would change are only listed.
+func Migrate(dryRun bool) error {
+	migrations, err := files.Migrate(dryRun)
+	if err != nil {
+		return err
+	}
+
+	if len(migrations) == 0 {
+		fmt.Println(pretty.Success("✓ All snapshots use the current format " + files.FormatVersion))
+		return nil
+	}
+
+	snapshotDir, err := files.SnapshotDir()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		line := relativePath(snapshotDir, m.From)
+		if m.To != m.From {
+			line += " -> " + relativePath(snapshotDir, m.To)
+		}
+		fmt.Println("  " + pretty.Gray(line))
+	}
+
+	if dryRun {
+		fmt.Printf(pretty.Warning("⊘ Found %d snapshot(s) to migrate (dry run, nothing written)\n"), len(migrations))
+		return nil
+	}
+
+	fmt.Printf(pretty.Success("✓ Migrated %d snapshot(s) to format %s\n"), len(migrations), files.FormatVersion)
+	return nil
+}
+
+// relativePath returns path relative to dir for display, or path itself if
+// it is not inside dir.
+func relativePath(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || !filepath.IsLocal(rel) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,58 @@
+package snapshots
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultTitleTemplate is used to derive a title when none is given.
+const DefaultTitleTemplate = "{test}"
+
+var (
+	autoTitleMu sync.Mutex
+	// autoTitleCounts tracks how many automatic titles each running test
+	// has generated so far.
+	autoTitleCounts = map[T]int{}
+)
+
+// AutoTitle derives a snapshot title for t from template. The following
+// placeholders are supported:
+//
+//	{test}  the full test name, including subtest path segments
+//	{name}  the last segment of the test name
+//	{n}     a per-test counter, starting at 1, incremented on every call
+//
+// An empty template uses DefaultTitleTemplate. Repeated titles within one
+// test are additionally disambiguated when the snapshot is stored.
+func AutoTitle(t T, template string) string {
+	if template == "" {
+		template = DefaultTitleTemplate
+	}
+
+	autoTitleMu.Lock()
+	n, seen := autoTitleCounts[t]
+	n++
+	autoTitleCounts[t] = n
+	autoTitleMu.Unlock()
+
+	if !seen {
+		t.Cleanup(func() {
+			autoTitleMu.Lock()
+			defer autoTitleMu.Unlock()
+			delete(autoTitleCounts, t)
+		})
+	}
+
+	testName := t.Name()
+	name := testName
+	if i := strings.LastIndex(testName, "/"); i >= 0 {
+		name = testName[i+1:]
+	}
+
+	return strings.NewReplacer(
+		"{test}", testName,
+		"{name}", name,
+		"{n}", strconv.Itoa(n),
+	).Replace(template)
+}
@@ -0,0 +1,58 @@
+package snapshots
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestAutoTitle_Templates(t *testing.T) {
+	tests := []struct {
+		name     string
+		testName string
+		template string
+		want     []string
+	}{
+		{"default", "TestUser", "", []string{"TestUser", "TestUser"}},
+		{"subtest", "TestUser/admin", "", []string{"TestUser/admin"}},
+		{"counter", "TestUser/admin", "{test}/{n}", []string{"TestUser/admin/1", "TestUser/admin/2", "TestUser/admin/3"}},
+		{"leaf name", "TestUser/admin", "{name} {n}", []string{"admin 1", "admin 2"}},
+		{"literal", "TestUser", "fixed", []string{"fixed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := &mockT{name: tt.testName}
+			for i, want := range tt.want {
+				if got := AutoTitle(mt, tt.template); got != want {
+					t.Errorf("call %d: AutoTitle() = %q, want %q", i+1, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoTitle_CounterResetsOnCleanup(t *testing.T) {
+	mt := &mockT{name: "TestCleanup"}
+
+	AutoTitle(mt, "{n}")
+	AutoTitle(mt, "{n}")
+	mt.runCleanups()
+
+	if got := AutoTitle(mt, "{n}"); got != "1" {
+		t.Errorf("expected counter to reset after cleanup, got %q", got)
+	}
+}
+
+func TestAutoTitle_SubtestSnapshotFile(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestUser/admin"}
+	Snap(mt, AutoTitle(mt, ""), "v1", "content")
+
+	if _, err := os.Stat(filepath.Join("__snapshots__", files.SnapshotFileName("TestUser/admin")+".snap.new")); err != nil {
+		t.Errorf("expected subtest snapshot file to be created: %v", err)
+	}
+}
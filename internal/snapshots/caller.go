@@ -0,0 +1,78 @@
+package snapshots
+
+import (
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// shutterPackages are the packages whose frames are skipped when looking for
+// the code that took a snapshot.
+var shutterPackages = map[string]bool{
+	"github.com/ptdewey/shutter":                    true,
+	"github.com/ptdewey/shutter/internal/snapshots": true,
+}
+
+// helpers holds the names of functions registered with MarkHelper.
+var helpers sync.Map // map[string]bool
+
+// MarkHelper registers the function skip frames above its caller as a
+// snapshot helper, so that snapshots are attributed to the helper's caller
+// instead, like t.Helper does for test failures.
+func MarkHelper(skip int) {
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return
+	}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		helpers.Store(fn.Name(), true)
+	}
+}
+
+// location identifies the code calling into shutter.
+type location struct {
+	file string
+	line int
+	// callee is the function called at line, as reported by runtime.Frame.
+	callee string
+}
+
+// callerLocation returns the location of the code calling into shutter,
+// skipping frames in shutter itself and, if skipHelpers is set, in functions
+// registered with MarkHelper. skip then skips that many more frames.
+func callerLocation(skip int, skipHelpers bool) (location, bool) {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var callee string
+	for {
+		frame, more := frames.Next()
+
+		// Tests of shutter's own packages are callers like any other
+		internal := shutterPackages[funcPackage(frame.Function)] && !strings.HasSuffix(frame.File, "_test.go")
+		_, helper := helpers.Load(frame.Function)
+		if !internal && !(skipHelpers && helper) {
+			if skip == 0 {
+				return location{file: frame.File, line: frame.Line, callee: callee}, true
+			}
+			skip--
+		}
+		callee = frame.Function
+
+		if !more {
+			return location{}, false
+		}
+	}
+}
+
+// funcPackage returns the import path of the package of a function as
+// reported by runtime.Frame, e.g. "example.com/pkg" for
+// "example.com/pkg.(*T).Method.func1".
+func funcPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
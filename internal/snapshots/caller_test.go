@@ -0,0 +1,95 @@
+package snapshots
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// snapThroughHelper takes a snapshot the way a shared test helper would.
+func snapThroughHelper(t T, title, content string) {
+	MarkHelper(0)
+	Snap(t, title, "v1", content)
+}
+
+// snapThroughWrapper takes a snapshot from an unregistered wrapper.
+func snapThroughWrapper(t T, title string, skip int) {
+	SnapSkip(t, skip, Info{}, title, "v1", "content")
+}
+
+// currentLine returns the line it is called from.
+func currentLine() int {
+	_, _, line, _ := runtime.Caller(1)
+	return line
+}
+
+func readNewSnapshot(t *testing.T, title string) *files.Snapshot {
+	t.Helper()
+	snap, err := files.ReadNew(files.SnapshotFileName(title))
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	return snap
+}
+
+func TestSnap_RecordsCallerLine(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestCaller"}
+	Snap(mt, "direct", "v1", "content")
+	wantLine := currentLine() - 1
+
+	snap := readNewSnapshot(t, "direct")
+	if snap.FileName != "caller_test.go" || snap.Line != wantLine {
+		t.Errorf("location = %s:%d, want caller_test.go:%d", snap.FileName, snap.Line, wantLine)
+	}
+}
+
+func TestSnap_SkipsRegisteredHelpers(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestHelper"}
+	snapThroughHelper(mt, "through_helper", "content")
+	wantLine := currentLine() - 1
+
+	snap := readNewSnapshot(t, "through_helper")
+	if snap.Line != wantLine {
+		t.Errorf("Line = %d, want %d", snap.Line, wantLine)
+	}
+}
+
+func TestSnapSkip(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestSkip"}
+	snapThroughWrapper(mt, "skipped", 1)
+	wantLine := currentLine() - 1
+
+	snap := readNewSnapshot(t, "skipped")
+	if snap.Line != wantLine {
+		t.Errorf("Line = %d, want %d", snap.Line, wantLine)
+	}
+
+	// Without skipping, the wrapper itself is recorded
+	snapThroughWrapper(mt, "not_skipped", 0)
+	if snap := readNewSnapshot(t, "not_skipped"); snap.Line == wantLine {
+		t.Errorf("expected the wrapper's line, got the caller's line %d", snap.Line)
+	}
+}
+
+func TestFuncPackage(t *testing.T) {
+	tests := map[string]string{
+		"github.com/ptdewey/shutter.Snap":                      "github.com/ptdewey/shutter",
+		"github.com/ptdewey/shutter/internal/snapshots.(*x).y": "github.com/ptdewey/shutter/internal/snapshots",
+		"github.com/ptdewey/shutter_test.TestSnap.func1":       "github.com/ptdewey/shutter_test",
+		"main.main":                       "main",
+		"example.com/v2/pkg.Generic[...]": "example.com/v2/pkg",
+	}
+
+	for function, want := range tests {
+		if got := funcPackage(function); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", function, got, want)
+		}
+	}
+}
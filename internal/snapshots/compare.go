@@ -0,0 +1,126 @@
+package snapshots
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// Status is the outcome of comparing a snapshot with the accepted one.
+type Status int
+
+const (
+	// StatusMatch means the content equals the accepted snapshot.
+	StatusMatch Status = iota
+	// StatusNew means there is no accepted snapshot yet.
+	StatusNew
+	// StatusChanged means the content differs from the accepted snapshot.
+	StatusChanged
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusMatch:
+		return "match"
+	case StatusNew:
+		return "new"
+	case StatusChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Result describes how a snapshot compares with the accepted one.
+type Result struct {
+	Status Status
+	// Name is the name the snapshot is stored under; see files.SnapshotName.
+	Name string
+	// Accepted is the accepted snapshot, or nil if Status is StatusNew.
+	Accepted *files.Snapshot
+	// New is the snapshot that was compared.
+	New *files.Snapshot
+	// Diff holds the line diff from Accepted to New if Status is
+	// StatusChanged, and is nil otherwise.
+	Diff []diff.DiffLine
+}
+
+// Compare compares snap with the accepted snapshot stored for it. It never
+// writes files. Comparing against an accepted snapshot written by a newer
+// format version is an error wrapping files.ErrNewerFormat.
+func Compare(snap *files.Snapshot) (*Result, error) {
+	name, err := files.SnapshotName(snap)
+	if err != nil {
+		return nil, err
+	}
+	return compareNamed(name, snap)
+}
+
+// CompareContent compares content with the accepted snapshot stored under
+// title, outside of any test. The snapshot is attributed to the calling file.
+func CompareContent(info Info, title, version, content string) (*Result, error) {
+	snap := &files.Snapshot{
+		Title:       title,
+		FileName:    "unknown",
+		Description: info.Description,
+		Metadata:    info.Metadata,
+		Sections:    info.Sections,
+		Content:     content,
+		Version:     version,
+	}
+	if loc, ok := callerLocation(0, true); ok {
+		setLocation(snap, loc)
+	}
+
+	return Compare(snap)
+}
+
+// compareNamed is Compare for a snapshot whose name is already resolved.
+func compareNamed(name string, snap *files.Snapshot) (*Result, error) {
+	result := &Result{Name: name, New: snap}
+
+	accepted, err := files.ReadAccepted(name)
+	if errors.Is(err, os.ErrNotExist) {
+		if legacyPath, ok := files.LegacyPath(snap); ok {
+			return nil, fmt.Errorf("accepted snapshot is stored as %s under an older naming scheme - run 'shutter migrate' to rename it", filepath.ToSlash(legacyPath))
+		}
+		result.Status = StatusNew
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result.Accepted = accepted
+
+	if files.CompareVersions(accepted.Version, snap.Version) > 0 {
+		// Never compare against a snapshot this version may not fully understand
+		return nil, &newerFormatError{accepted: accepted.Version, supported: snap.Version}
+	}
+
+	if accepted.Content == snap.Content {
+		result.Status = StatusMatch
+		return result, nil
+	}
+
+	result.Status = StatusChanged
+	result.Diff = diff.Histogram(accepted.Content, snap.Content)
+	return result, nil
+}
+
+// newerFormatError is returned by Compare for accepted snapshots written by a
+// newer format version.
+type newerFormatError struct {
+	accepted, supported string
+}
+
+func (e *newerFormatError) Error() string {
+	return fmt.Sprintf("accepted snapshot uses format %s, newer than the supported format %s", e.accepted, e.supported)
+}
+
+func (e *newerFormatError) Unwrap() error {
+	return files.ErrNewerFormat
+}
@@ -0,0 +1,132 @@
+package snapshots
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestCompare(t *testing.T) {
+	setupTestDir(t)
+	saveAccepted(t, "compared", "line one\nline two")
+
+	tests := []struct {
+		title    string
+		content  string
+		want     Status
+		wantDiff bool
+	}{
+		{"compared", "line one\nline two", StatusMatch, false},
+		{"compared", "line one\nline 2", StatusChanged, true},
+		{"unseen", "content", StatusNew, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want.String(), func(t *testing.T) {
+			snap := &files.Snapshot{Title: tt.title, Test: "TestExample", Content: tt.content, Version: "v1"}
+			result, err := Compare(snap)
+			if err != nil {
+				t.Fatalf("Compare failed: %v", err)
+			}
+			if result.Status != tt.want {
+				t.Errorf("Status = %s, want %s", result.Status, tt.want)
+			}
+			if (result.Accepted == nil) != (tt.want == StatusNew) {
+				t.Errorf("unexpected accepted snapshot %+v", result.Accepted)
+			}
+			if (len(result.Diff) > 0) != tt.wantDiff {
+				t.Errorf("unexpected diff %+v", result.Diff)
+			}
+			if result.New != snap || result.Name != files.SnapshotFileName(tt.title) {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+
+	if _, err := os.Stat("__snapshots__/compared.snap.new"); !os.IsNotExist(err) {
+		t.Errorf("expected Compare not to write files, got %v", err)
+	}
+}
+
+func TestCompare_LeavesSnapshotDirUntouched(t *testing.T) {
+	setupTestDir(t)
+
+	if _, err := Compare(&files.Snapshot{Title: "unseen", Content: "content", Version: "v1"}); err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if _, err := os.Stat("__snapshots__"); !os.IsNotExist(err) {
+		t.Fatalf("expected Compare not to create the snapshot directory, got %v", err)
+	}
+
+	saveAccepted(t, "compared", "content")
+	legacy := &files.Snapshot{Title: "Legacy: Title", Content: "content", Version: "v1"}
+	if err := os.WriteFile(filepath.Join("__snapshots__", "legacy:_title.snap"), []byte(legacy.Serialize()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	before := listDir(t, "__snapshots__")
+
+	for _, snap := range []*files.Snapshot{
+		{Title: "compared", Content: "changed", Version: "v1"},
+		{Title: "unseen", Content: "content", Version: "v1"},
+		legacy,
+	} {
+		_, _ = Compare(snap)
+	}
+
+	if after := listDir(t, "__snapshots__"); !slices.Equal(before, after) {
+		t.Errorf("Compare changed the snapshot directory from %q to %q", before, after)
+	}
+}
+
+// listDir returns the names of the files in dir.
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names
+}
+
+func TestCompare_Diff(t *testing.T) {
+	setupTestDir(t)
+	saveAccepted(t, "diffed", "a\nb")
+
+	result, err := Compare(&files.Snapshot{Title: "diffed", Content: "a\nc", Version: "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []diff.DiffLine{
+		{Line: "a", Kind: diff.DiffShared, OldNumber: 1, NewNumber: 1},
+		{Line: "b", Kind: diff.DiffOld, OldNumber: 2},
+		{Line: "c", Kind: diff.DiffNew, NewNumber: 2},
+	}
+	if len(result.Diff) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", result.Diff, want)
+	}
+	for i := range want {
+		if result.Diff[i] != want[i] {
+			t.Errorf("Diff[%d] = %+v, want %+v", i, result.Diff[i], want[i])
+		}
+	}
+}
+
+func TestCompare_NewerFormat(t *testing.T) {
+	setupTestDir(t)
+	saveAcceptedVersion(t, "from_the_future", "9.0.0", "content")
+
+	_, err := Compare(&files.Snapshot{Title: "from_the_future", Content: "content", Version: "0.2.0"})
+	if !errors.Is(err, files.ErrNewerFormat) {
+		t.Errorf("expected ErrNewerFormat, got %v", err)
+	}
+}
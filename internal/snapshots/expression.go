@@ -0,0 +1,143 @@
+package snapshots
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// valueArgs maps the functions taking snapshots to the index of the argument
+// holding the snapshotted value.
+var valueArgs = map[string]int{
+	"github.com/ptdewey/shutter.Snap":                              2,
+	"github.com/ptdewey/shutter.SnapMany":                          2,
+	"github.com/ptdewey/shutter.SnapString":                        2,
+	"github.com/ptdewey/shutter.SnapSections":                      2,
+	"github.com/ptdewey/shutter.SnapJSON":                          2,
+	"github.com/ptdewey/shutter.SnapJSONBytes":                     2,
+	"github.com/ptdewey/shutter.SnapJSONReader":                    2,
+	"github.com/ptdewey/shutter.SnapJSONValue":                     2,
+	"github.com/ptdewey/shutter.Compare":                           1,
+	"github.com/ptdewey/shutter.CompareString":                     1,
+	"github.com/ptdewey/shutter/internal/snapshots.Snap":           3,
+	"github.com/ptdewey/shutter/internal/snapshots.SnapSkip":       5,
+	"github.com/ptdewey/shutter/internal/snapshots.CompareContent": 3,
+}
+
+// parsedFile is a parsed Go source file.
+type parsedFile struct {
+	fset *token.FileSet
+	file *ast.File
+}
+
+// parsedFiles caches the source files parsed by sourceExpression, by path.
+var parsedFiles sync.Map // map[string]*parsedFile
+
+// setLocation records loc as the source of snap.
+func setLocation(snap *files.Snapshot, loc location) {
+	snap.FileName = filepath.Base(loc.file)
+	snap.Line = loc.line
+	snap.Expression = sourceExpression(loc)
+}
+
+// sourceExpression returns the source of the value snapshotted by the call at
+// loc, such as `api.GetUser("123")`. For calls to helpers, the whole helper
+// call is returned. It returns an empty string if the source is unavailable.
+func sourceExpression(loc location) string {
+	name := calleeName(loc.callee)
+	if name == "" {
+		return ""
+	}
+
+	parsed, ok := parseSource(loc.file)
+	if !ok {
+		return ""
+	}
+
+	call := findCall(parsed, loc.line, name)
+	if call == nil {
+		return ""
+	}
+
+	var expr ast.Expr = call
+	if arg, ok := valueArgs[loc.callee]; ok {
+		if len(call.Args) <= arg {
+			return ""
+		}
+		expr = call.Args[arg]
+	}
+
+	var sb strings.Builder
+	if err := format.Node(&sb, parsed.fset, expr); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+// calleeName returns the name a function is called by, e.g. "Method" for
+// "example.com/pkg.(*T).Method". Function literals, reported as "func1" and
+// the like, have no name.
+func calleeName(function string) string {
+	function = strings.TrimSuffix(function, "[...]")
+	name := function[strings.LastIndex(function, ".")+1:]
+	if !token.IsIdentifier(name) || len(name) > 4 && strings.HasPrefix(name, "func") && strings.Trim(name[4:], "0123456789") == "" {
+		return ""
+	}
+	return name
+}
+
+// parseSource parses the Go file at path, caching the result.
+func parseSource(path string) (*parsedFile, bool) {
+	if cached, ok := parsedFiles.Load(path); ok {
+		parsed := cached.(*parsedFile)
+		return parsed, parsed.file != nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		file = nil
+	}
+
+	parsed := &parsedFile{fset: fset, file: file}
+	parsedFiles.Store(path, parsed)
+	return parsed, file != nil
+}
+
+// findCall returns the innermost call of a function named name spanning line.
+func findCall(parsed *parsedFile, line int, name string) *ast.CallExpr {
+	var found *ast.CallExpr
+	ast.Inspect(parsed.file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || !isCallTo(call.Fun, name) {
+			return true
+		}
+		if parsed.fset.Position(call.Pos()).Line <= line && line <= parsed.fset.Position(call.End()).Line {
+			found = call
+		}
+		return true
+	})
+	return found
+}
+
+// isCallTo reports whether fun refers to a function or method named name,
+// possibly instantiated with type arguments.
+func isCallTo(fun ast.Expr, name string) bool {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		return f.Name == name
+	case *ast.SelectorExpr:
+		return f.Sel.Name == name
+	case *ast.IndexExpr:
+		return isCallTo(f.X, name)
+	case *ast.IndexListExpr:
+		return isCallTo(f.X, name)
+	}
+	return false
+}
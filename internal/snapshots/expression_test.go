@@ -0,0 +1,67 @@
+package snapshots
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnap_RecordsExpression(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestExpression"}
+	Snap(mt, "direct_expression", "v1", strings.Repeat("ab", 2))
+
+	if got := readNewSnapshot(t, "direct_expression").Expression; got != `strings.Repeat("ab", 2)` {
+		t.Errorf("Expression = %q, want the content argument", got)
+	}
+}
+
+func TestSnap_RecordsHelperCall(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestHelperExpression"}
+	snapThroughHelper(mt, "helper_expression",
+		"content")
+
+	want := "snapThroughHelper(mt, \"helper_expression\",\n\t\"content\")"
+	if got := readNewSnapshot(t, "helper_expression").Expression; got != want {
+		t.Errorf("Expression = %q, want %q", got, want)
+	}
+}
+
+func TestCompareContent_RecordsExpression(t *testing.T) {
+	setupTestDir(t)
+
+	content := "value"
+	result, err := CompareContent(Info{}, "compared", "v1", content)
+	if err != nil {
+		t.Fatalf("CompareContent failed: %v", err)
+	}
+	if result.New.Expression != "content" {
+		t.Errorf("Expression = %q, want %q", result.New.Expression, "content")
+	}
+}
+
+func TestCalleeName(t *testing.T) {
+	tests := map[string]string{
+		"github.com/ptdewey/shutter.Snap":   "Snap",
+		"example.com/pkg.(*Suite).snapUser": "snapUser",
+		"example.com/pkg.snapAs[...]":       "snapAs",
+		"example.com/pkg.TestUser.func1":    "",
+		"example.com/pkg.TestUser.func1.2":  "",
+		"example.com/pkg.function":          "function",
+	}
+
+	for function, want := range tests {
+		if got := calleeName(function); got != want {
+			t.Errorf("calleeName(%q) = %q, want %q", function, got, want)
+		}
+	}
+}
+
+func TestSourceExpression_Unavailable(t *testing.T) {
+	loc := location{file: "does_not_exist.go", line: 1, callee: "github.com/ptdewey/shutter.Snap"}
+	if got := sourceExpression(loc); got != "" {
+		t.Errorf("expected no expression for a missing file, got %q", got)
+	}
+}
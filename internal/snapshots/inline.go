@@ -0,0 +1,106 @@
+package snapshots
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/inline"
+)
+
+// SnapInline compares content against an expected value written inline in
+// the calling test file.
+func SnapInline(t T, expected, content string) {
+	t.Helper()
+
+	// Helpers are not skipped: the location must be the SnapInline call
+	// holding the literal to rewrite
+	loc, ok := callerLocation(0, false)
+	if !ok {
+		t.Error("inline snapshot: failed to determine the calling source file")
+		return
+	}
+
+	SnapInlineAt(t, loc.file, loc.line, expected, content)
+}
+
+// SnapInlineAt compares content against the expected value of the SnapInline
+// call at file:line. Both values are normalized with inline.Normalize.
+func SnapInlineAt(t T, file string, line int, expected, content string) {
+	t.Helper()
+
+	// Record paths relative to the package directory so pending updates stay
+	// valid wherever the module is checked out
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, file); err == nil && filepath.IsLocal(rel) {
+			file = rel
+		}
+	}
+
+	update := &files.InlineUpdate{
+		File: file,
+		Line: line,
+		Test: t.Name(),
+		Old:  inline.Normalize(expected),
+		New:  inline.Normalize(content),
+	}
+
+	mode, err := CurrentUpdateMode()
+	if err != nil {
+		t.Error(fmt.Sprintf("inline snapshot %s: %v", update.Location(), err))
+		return
+	}
+	if _, err := CurrentVerbosity(); err != nil {
+		t.Error(fmt.Sprintf("inline snapshot %s: %v", update.Location(), err))
+		return
+	}
+
+	if update.Old == update.New {
+		recordOutcome(t, outcomeUnchanged, "")
+		// Drop an update left behind by an earlier failing run. UpdateNo
+		// never touches the snapshot directory
+		if mode != UpdateNo {
+			if err := files.RemoveInline(update); err != nil {
+				t.Log(fmt.Sprintf("inline snapshot %s: failed to remove stale update: %v", update.Location(), err))
+			}
+		}
+		return
+	}
+
+	accepted, snapshot := update.Snapshots()
+	rendered := renderNew(snapshot)
+	if update.Old != "" {
+		rendered = renderDiff(accepted, snapshot, diff.Histogram(accepted.Content, snapshot.Content))
+	}
+
+	writeSource := mode == UpdateAlways || (mode == UpdateNew && update.Old == "")
+	result := outcomeChanged
+	if update.Old == "" {
+		result = outcomeNew
+	}
+
+	switch {
+	case writeSource:
+		if err := inline.Rewrite(update.File, []*files.InlineUpdate{update}); err != nil {
+			report(t, true, fmt.Sprintf("inline snapshot %s: failed to update source: %v", update.Location(), err), rendered)
+			return
+		}
+		if err := files.RemoveInline(update); err != nil {
+			t.Log(fmt.Sprintf("inline snapshot %s: failed to remove stale update: %v", update.Location(), err))
+		}
+		recordOutcome(t, result, "")
+		report(t, false, fmt.Sprintf("inline snapshot %s updated (%s=%s)", update.Location(), UpdateEnvVar, mode), rendered)
+	case mode == UpdateNo:
+		recordOutcome(t, result, "")
+		report(t, true, "inline snapshot mismatch - rerun with "+UpdateEnvVar+"=pending and run 'shutter review' to update", rendered)
+	default:
+		if err := files.SaveInline(update); err != nil {
+			report(t, true, fmt.Sprint("failed to save inline snapshot: ", err), rendered)
+			return
+		}
+		recordOutcome(t, result, update.Location())
+		report(t, true, "inline snapshot mismatch - run 'shutter review' to update", rendered)
+	}
+}
@@ -0,0 +1,113 @@
+package snapshots
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+const inlineSource = "package example\n\nfunc TestExample(t *testing.T) {\n\tshutter.SnapInline(t, value, \"old\")\n}\n"
+
+func writeInlineSource(t *testing.T) string {
+	t.Helper()
+
+	if err := os.WriteFile("example_test.go", []byte(inlineSource), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "example_test.go"
+}
+
+func TestSnapInline_Match(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestInline"}
+	SnapInlineAt(mt, "example_test.go", 4, "\n\t\tsame\n\t", "same\n")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if _, err := os.Stat("__snapshots__"); !os.IsNotExist(err) {
+		t.Errorf("expected a passing inline snapshot not to create the snapshot directory, got %v", err)
+	}
+}
+
+func TestSnapInline_NoKeepsStaleUpdate(t *testing.T) {
+	setupTestDir(t)
+	file := writeInlineSource(t)
+
+	mt := &mockT{name: "TestInline"}
+	SnapInlineAt(mt, file, 4, "old", "new")
+
+	// A passing run in UpdateNo mode leaves the snapshot directory alone
+	t.Setenv(UpdateEnvVar, "no")
+	SnapInlineAt(mt, file, 4, "new", "new")
+	if updates, _ := files.ListInline(); len(updates) != 1 {
+		t.Errorf("expected the pending update to be kept, got %+v", updates)
+	}
+}
+
+func TestSnapInline_MismatchRecordsUpdate(t *testing.T) {
+	setupTestDir(t)
+	file := writeInlineSource(t)
+
+	mt := &mockT{name: "TestInline"}
+	SnapInlineAt(mt, file, 4, "old", "new")
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "shutter review") {
+		t.Fatalf("expected a review error, got %v", mt.errors)
+	}
+
+	updates, err := files.ListInline()
+	if err != nil {
+		t.Fatalf("ListInline failed: %v", err)
+	}
+	if len(updates) != 1 || updates[0].Old != "old" || updates[0].New != "new" || updates[0].Test != "TestInline" {
+		t.Fatalf("unexpected pending updates: %+v", updates)
+	}
+
+	// A passing run clears the pending update
+	SnapInlineAt(mt, file, 4, "new", "new")
+	updates, _ = files.ListInline()
+	if len(updates) != 0 {
+		t.Errorf("expected pending update to be removed, got %+v", updates)
+	}
+}
+
+func TestSnapInline_AlwaysRewritesSource(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "always")
+	file := writeInlineSource(t)
+
+	mt := &mockT{name: "TestInline"}
+	SnapInlineAt(mt, file, 4, "old", "new")
+
+	if len(mt.errors) != 0 {
+		t.Fatalf("expected no errors, got %v", mt.errors)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `shutter.SnapInline(t, value, "new")`) {
+		t.Errorf("expected source to be rewritten, got:\n%s", data)
+	}
+}
+
+func TestSnapInline_NoWritesNothing(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "no")
+	file := writeInlineSource(t)
+
+	mt := &mockT{name: "TestInline"}
+	SnapInlineAt(mt, file, 4, "old", "new")
+
+	if len(mt.errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", mt.errors)
+	}
+	if updates, _ := files.ListInline(); len(updates) != 0 {
+		t.Errorf("expected no pending updates, got %+v", updates)
+	}
+}
@@ -0,0 +1,156 @@
+package snapshots
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
+	"github.com/ptdewey/shutter/internal/pretty"
+)
+
+// Verbosity controls how much of a new or changed snapshot is reported.
+type Verbosity int
+
+const (
+	// VerbosityFull reports the full snapshot or diff in a box.
+	VerbosityFull Verbosity = iota
+	// VerbosityCompact reports only the changed hunks of a diff and the
+	// first lines of a new snapshot.
+	VerbosityCompact
+	// VerbositySummary reports a single line per snapshot.
+	VerbositySummary
+)
+
+// VerbosityEnvVar is the environment variable used to select the verbosity.
+const VerbosityEnvVar = "SHUTTER_VERBOSITY"
+
+var (
+	reportMu     sync.Mutex
+	verbosity    Verbosity
+	verbositySet bool
+	// output receives reports instead of the test log when set.
+	output io.Writer
+)
+
+func (v Verbosity) String() string {
+	switch v {
+	case VerbosityFull:
+		return "full"
+	case VerbosityCompact:
+		return "compact"
+	case VerbositySummary:
+		return "summary"
+	default:
+		return fmt.Sprintf("Verbosity(%d)", int(v))
+	}
+}
+
+// ParseVerbosity parses the textual form of a verbosity as accepted by the
+// SHUTTER_VERBOSITY environment variable.
+func ParseVerbosity(s string) (Verbosity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "full":
+		return VerbosityFull, nil
+	case "compact":
+		return VerbosityCompact, nil
+	case "summary":
+		return VerbositySummary, nil
+	default:
+		return VerbosityFull, fmt.Errorf("invalid %s value %q (expected full, compact or summary)", VerbosityEnvVar, s)
+	}
+}
+
+// SetVerbosity overrides the verbosity for the current process. A verbosity
+// set here takes precedence over the SHUTTER_VERBOSITY environment variable.
+func SetVerbosity(v Verbosity) {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	verbosity = v
+	verbositySet = true
+}
+
+// SetOutput sends reports of new and changed snapshots to w instead of the
+// log of the test that took them. A nil w restores the default.
+func SetOutput(w io.Writer) {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	output = w
+}
+
+// resetReporting clears settings made with SetVerbosity and SetOutput.
+func resetReporting() {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	verbosity = VerbosityFull
+	verbositySet = false
+	output = nil
+}
+
+// CurrentVerbosity returns the effective verbosity.
+func CurrentVerbosity() (Verbosity, error) {
+	reportMu.Lock()
+	v, set := verbosity, verbositySet
+	reportMu.Unlock()
+
+	if set {
+		return v, nil
+	}
+	return ParseVerbosity(os.Getenv(VerbosityEnvVar))
+}
+
+// renderDiff renders a changed snapshot at the current verbosity.
+func renderDiff(accepted, snapshot *files.Snapshot, diffLines []diff.DiffLine) string {
+	// An invalid verbosity is reported by the caller through CurrentVerbosity
+	v, _ := CurrentVerbosity()
+	switch v {
+	case VerbosityCompact:
+		return pretty.CompactDiff(snapshot, diffLines)
+	case VerbositySummary:
+		return pretty.DiffSummary(snapshot, diffLines)
+	default:
+		return pretty.DiffSnapshotBox(accepted, snapshot, diffLines)
+	}
+}
+
+// renderNew renders a new snapshot at the current verbosity.
+func renderNew(snapshot *files.Snapshot) string {
+	v, _ := CurrentVerbosity()
+	switch v {
+	case VerbosityCompact:
+		return pretty.CompactNewSnapshot(snapshot)
+	case VerbositySummary:
+		return pretty.NewSnapshotSummary(snapshot)
+	default:
+		return pretty.NewSnapshotBox(snapshot)
+	}
+}
+
+// report attaches a rendered snapshot to msg, failing the test if fail is
+// set. When an output writer is configured, the rendering goes there and
+// only msg reaches the test.
+func report(t T, fail bool, msg, rendered string) {
+	t.Helper()
+
+	rendered = strings.TrimRight(rendered, "\n")
+
+	reportMu.Lock()
+	w := output
+	if w != nil {
+		fmt.Fprintln(w, rendered)
+	}
+	reportMu.Unlock()
+
+	if w == nil {
+		msg += "\n" + rendered
+	}
+
+	if fail {
+		t.Error(msg)
+	} else {
+		t.Log(msg)
+	}
+}
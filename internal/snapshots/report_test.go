@@ -0,0 +1,99 @@
+package snapshots
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseVerbosity(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Verbosity
+		wantErr bool
+	}{
+		{"", VerbosityFull, false},
+		{"full", VerbosityFull, false},
+		{"compact", VerbosityCompact, false},
+		{"SUMMARY", VerbositySummary, false},
+		{"loud", VerbosityFull, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseVerbosity(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseVerbosity(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseVerbosity(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnap_MismatchReportedInTestError(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv("NO_COLOR", "1")
+	saveAccepted(t, "reported", "line one\nline two")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "reported", "v1", "line one\nline 2")
+
+	if len(mt.errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", mt.errors)
+	}
+	for _, want := range []string{"snapshot mismatch", "Snapshot Diff", "- line two", "+ line 2"} {
+		if !strings.Contains(mt.errors[0], want) {
+			t.Errorf("expected error to contain %q, got:\n%s", want, mt.errors[0])
+		}
+	}
+}
+
+func TestSnap_VerbositySummary(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(VerbosityEnvVar, "summary")
+	saveAccepted(t, "summarized", "line one\nline two")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "summarized", "v1", "line one\nline 2\nline 3")
+
+	if len(mt.errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", mt.errors)
+	}
+	lines := strings.Split(mt.errors[0], "\n")
+	if len(lines) != 2 || lines[1] != "summarized (+2 -1)" {
+		t.Errorf("expected a one-line summary, got:\n%s", mt.errors[0])
+	}
+}
+
+func TestSnap_InvalidVerbosity(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(VerbosityEnvVar, "loud")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "invalid_verbosity", "v1", "content")
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], VerbosityEnvVar) {
+		t.Errorf("expected a verbosity error, got %v", mt.errors)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	setupTestDir(t)
+	t.Cleanup(resetReporting)
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	SetVerbosity(VerbosityCompact)
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "redirected", "v1", "content")
+
+	if len(mt.errors) != 1 || strings.Contains(mt.errors[0], "\n") {
+		t.Errorf("expected only the message in the test error, got %v", mt.errors)
+	}
+	if !strings.Contains(buf.String(), "redirected") || !strings.Contains(buf.String(), "content") {
+		t.Errorf("expected the snapshot in the output writer, got:\n%s", buf.String())
+	}
+}
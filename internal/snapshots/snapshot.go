@@ -1,13 +1,11 @@
 package snapshots
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
-	"runtime"
 
-	"github.com/ptdewey/shutter/internal/diff"
 	"github.com/ptdewey/shutter/internal/files"
-	"github.com/ptdewey/shutter/internal/pretty"
 )
 
 type T interface {
@@ -21,62 +19,195 @@ type T interface {
 	Cleanup(func())
 }
 
+// Info is descriptive information stored in a snapshot's header for
+// reviewers. It does not take part in comparisons.
+type Info struct {
+	Description string
+	Metadata    map[string]string
+	// Sections marks content made of SnapSections sections.
+	Sections bool
+}
+
 func Snap(t T, title, version, content string) {
 	t.Helper()
-	testName := t.Name()
-
-	// Capture the caller's filename by walking up the call stack
-	// to find the first file that's not shutter.go
-	fileName := "unknown"
-	for i := 1; i < 10; i++ {
-		_, file, _, ok := runtime.Caller(i)
-		if !ok {
-			break
-		}
-		baseName := filepath.Base(file)
-		// Skip frames within shutter.go to get to the actual test file
-		if baseName != "shutter.go" {
-			fileName = baseName
-			break
-		}
+	SnapSkip(t, 0, Info{}, title, version, content)
+}
+
+// SnapSkip is like Snap, but attributes the snapshot to the caller skip frames
+// above the test code calling into shutter (see callerLocation) and stores
+// info in its header.
+func SnapSkip(t T, skip int, info Info, title, version, content string) {
+	t.Helper()
+
+	snapshot := &files.Snapshot{
+		Title:       title,
+		Test:        t.Name(),
+		FileName:    "unknown",
+		Description: info.Description,
+		Metadata:    info.Metadata,
+		Sections:    info.Sections,
+		Content:     content,
+		Version:     version,
+	}
+	if loc, ok := callerLocation(skip, true); ok {
+		setLocation(snapshot, loc)
 	}
 
-	SnapWithTitle(t, title, testName, fileName, version, content)
+	takeSnapshot(t, snapshot)
 }
 
-func SnapWithTitle(t T, title, testName, fileName, version, content string) {
+func SnapWithTitle(t T, title, testName, fileName string, line int, version, content string) {
 	t.Helper()
 
-	snapshot := &files.Snapshot{
+	takeSnapshot(t, &files.Snapshot{
 		Title:    title,
 		Test:     testName,
 		FileName: fileName,
+		Line:     line,
 		Content:  content,
 		Version:  version,
+	})
+}
+
+// takeSnapshot compares snapshot with the accepted one, failing t and saving
+// it according to the update mode if they differ.
+func takeSnapshot(t T, snapshot *files.Snapshot) {
+	t.Helper()
+
+	title, err := claimTitle(t, snapshot.Test, snapshot.FileName, snapshot.Title)
+	if err != nil {
+		t.Error(err.Error())
+		return
+	}
+	snapshot.Title = title
+	version := snapshot.Version
+
+	name, err := files.SnapshotName(snapshot)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+
+	mode, err := CurrentUpdateMode()
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	if _, err := CurrentVerbosity(); err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+
+	if err := files.RecordVisited(name); err != nil {
+		t.Log(fmt.Sprintf("snapshot %q: failed to record usage: %v", title, err))
 	}
 
-	accepted, err := files.ReadAccepted(title)
-	if err == nil {
-		if accepted.Content == content {
+	result, err := compareNamed(name, snapshot)
+	var newer *newerFormatError
+	if errors.As(err, &newer) {
+		// Never overwrite a snapshot this version may not fully understand
+		t.Error(fmt.Sprintf("snapshot %q: %v - upgrade shutter", title, err))
+		return
+	}
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+
+	if result.Accepted != nil {
+		accepted := result.Accepted
+		if files.CompareVersions(accepted.Version, version) < 0 {
+			t.Log(fmt.Sprintf("snapshot %q uses format %s (current %s) - run 'shutter migrate' to upgrade",
+				title, versionOrUnknown(accepted.Version), version))
+		}
+
+		if result.Status == StatusMatch {
+			// UpdateNo never touches the snapshot directory
+			if mode != UpdateNo {
+				removeStaleNew(t, title, name)
+			}
+			recordOutcome(t, outcomeUnchanged, "")
 			return
 		}
 
+		rendered := renderDiff(accepted, snapshot, result.Diff)
+
+		switch mode {
+		case UpdateAlways:
+			if err := files.SaveSnapshot(snapshot, "accepted"); err != nil {
+				report(t, true, fmt.Sprint("failed to save snapshot: ", err), rendered)
+				return
+			}
+			removeStaleNew(t, title, name)
+			recordOutcome(t, outcomeChanged, "")
+			report(t, false, fmt.Sprintf("snapshot %q updated (%s=%s)", title, UpdateEnvVar, mode), rendered)
+		case UpdateNo:
+			recordOutcome(t, outcomeChanged, "")
+			report(t, true, "snapshot mismatch - rerun with "+UpdateEnvVar+"=pending and run 'shutter review' to update", rendered)
+		default:
+			if err := files.SaveSnapshot(snapshot, "new"); err != nil {
+				report(t, true, fmt.Sprint("failed to save snapshot: ", err), rendered)
+				return
+			}
+			recordOutcome(t, outcomeChanged, pendingPath(name))
+			report(t, true, "snapshot mismatch - run 'shutter review' to update", rendered)
+		}
+		return
+	}
+
+	rendered := renderNew(snapshot)
+
+	switch mode {
+	case UpdateAlways, UpdateNew:
+		if err := files.SaveSnapshot(snapshot, "accepted"); err != nil {
+			report(t, true, fmt.Sprint("failed to save snapshot: ", err), rendered)
+			return
+		}
+		removeStaleNew(t, title, name)
+		recordOutcome(t, outcomeNew, "")
+		report(t, false, fmt.Sprintf("new snapshot %q accepted (%s=%s)", title, UpdateEnvVar, mode), rendered)
+	case UpdateNo:
+		recordOutcome(t, outcomeNew, "")
+		report(t, true, "new snapshot not written - rerun with "+UpdateEnvVar+"=pending and run 'shutter review' to accept", rendered)
+	default:
 		if err := files.SaveSnapshot(snapshot, "new"); err != nil {
-			t.Error("failed to save snapshot:", err)
+			report(t, true, fmt.Sprint("failed to save snapshot: ", err), rendered)
 			return
 		}
+		recordOutcome(t, outcomeNew, pendingPath(name))
+		report(t, true, "new snapshot created - run 'shutter review' to accept", rendered)
+	}
+}
+
+// removeStaleNew removes a pending snapshot left behind by an earlier failing
+// run once the accepted snapshot matches the content again.
+func removeStaleNew(t T, title, name string) {
+	t.Helper()
 
-		diffLines := diff.Histogram(accepted.Content, snapshot.Content)
-		fmt.Println(pretty.DiffSnapshotBox(accepted, snapshot, diffLines))
-		t.Error("snapshot mismatch - run 'shutter review' to update")
+	removed, err := files.RemoveStaleNew(name)
+	if err != nil {
+		t.Log(fmt.Sprintf("snapshot %q: failed to remove stale pending snapshot: %v", title, err))
 		return
 	}
+	if removed {
+		t.Log(fmt.Sprintf("snapshot %q matches again - removed stale pending snapshot", title))
+	}
+}
 
-	if err := files.SaveSnapshot(snapshot, "new"); err != nil {
-		t.Error("failed to save snapshot:", err)
-		return
+// pendingPath returns the path of the pending file of a snapshot for display.
+func pendingPath(name string) string {
+	filePath, err := files.SnapshotPath(name, "new")
+	if err != nil {
+		return name
 	}
+	return filepath.ToSlash(filePath)
+}
 
-	fmt.Println(pretty.NewSnapshotBox(snapshot))
-	t.Error("new snapshot created - run 'shutter review' to accept")
+// versionOrUnknown returns version, or a placeholder for snapshots written
+// before the format version was recorded.
+func versionOrUnknown(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/ptdewey/shutter/internal/files"
@@ -97,6 +98,13 @@ func setupTestDir(t *testing.T) string {
 		t.Fatalf("failed to change to temp dir: %v", err)
 	}
 
+	// Pin the update mode so results don't depend on CI detection
+	t.Setenv(UpdateEnvVar, "pending")
+	// Keep records of visited snapshots out of the real temp directory
+	t.Setenv("TMPDIR", filepath.Join(tmpDir, "tmp"))
+	files.ResetVisited()
+	resetTitles()
+
 	// Cleanup function
 	t.Cleanup(func() {
 		os.Chdir(originalDir)
@@ -122,7 +130,7 @@ func TestSnap_NewSnapshot(t *testing.T) {
 	}
 
 	// Verify snapshot file was created
-	snapPath := filepath.Join("__snapshots__", "test_snap.snap.new")
+	snapPath := filepath.Join("__snapshots__", files.SnapshotFileName("test_snap")+".snap.new")
 	if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 		t.Error("expected snapshot file to be created")
 	}
@@ -152,6 +160,37 @@ func TestSnap_MatchingSnapshot(t *testing.T) {
 	}
 }
 
+func TestSnap_MatchRemovesStalePendingSnapshot(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "flaky_test", "v1", "expected content")
+	if err := files.AcceptSnapshot(files.SnapshotFileName("flaky_test")); err != nil {
+		t.Fatalf("failed to accept snapshot: %v", err)
+	}
+
+	// A failing run leaves a pending snapshot behind
+	resetTitles()
+	Snap(&mockT{name: "TestExample"}, "flaky_test", "v1", "unexpected content")
+	if _, err := files.ReadNew(files.SnapshotFileName("flaky_test")); err != nil {
+		t.Fatalf("expected a pending snapshot: %v", err)
+	}
+
+	resetTitles()
+	mt = &mockT{name: "TestExample"}
+	Snap(mt, "flaky_test", "v1", "expected content")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if _, err := files.ReadNew(files.SnapshotFileName("flaky_test")); !os.IsNotExist(err) {
+		t.Errorf("expected stale pending snapshot to be removed, got %v", err)
+	}
+	if len(mt.logs) != 1 || !strings.Contains(mt.logs[0], "removed stale pending snapshot") {
+		t.Errorf("expected a log about the removed snapshot, got %v", mt.logs)
+	}
+}
+
 func TestSnap_MismatchedSnapshot(t *testing.T) {
 	setupTestDir(t)
 
@@ -180,7 +219,7 @@ func TestSnap_MismatchedSnapshot(t *testing.T) {
 	}
 
 	// Verify new snapshot file was created
-	snapPath := filepath.Join("__snapshots__", "mismatched_test.snap.new")
+	snapPath := filepath.Join("__snapshots__", files.SnapshotFileName("mismatched_test")+".snap.new")
 	if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 		t.Error("expected new snapshot file to be created")
 	}
@@ -193,7 +232,7 @@ func TestSnap_CallerDetection(t *testing.T) {
 	Snap(mt, "caller_test", "v1", "test content")
 
 	// Read the created snapshot
-	snap, err := files.ReadSnapshot("caller_test", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("caller_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -213,10 +252,10 @@ func TestSnapWithTitle_CreatesCorrectSnapshot(t *testing.T) {
 	setupTestDir(t)
 
 	mt := &mockT{name: "TestExample"}
-	SnapWithTitle(mt, "custom_title", "TestExample", "test.go", "v1", "custom content")
+	SnapWithTitle(mt, "custom_title", "TestExample", "test.go", 0, "v1", "custom content")
 
 	// Read the snapshot
-	snap, err := files.ReadSnapshot("custom_title", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("custom_title"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -254,7 +293,7 @@ func TestSnapWithTitle_MatchingContent(t *testing.T) {
 	}
 
 	mt := &mockT{name: "TestMatch"}
-	SnapWithTitle(mt, "match_title", "TestMatch", "test.go", "v1", "same content")
+	SnapWithTitle(mt, "match_title", "TestMatch", "test.go", 0, "v1", "same content")
 
 	// Should not error (content matches)
 	if len(mt.errors) != 0 {
@@ -278,7 +317,7 @@ func TestSnapWithTitle_MismatchedContent(t *testing.T) {
 	}
 
 	mt := &mockT{name: "TestMismatch"}
-	SnapWithTitle(mt, "mismatch_title", "TestMismatch", "test.go", "v1", "new content")
+	SnapWithTitle(mt, "mismatch_title", "TestMismatch", "test.go", 0, "v1", "new content")
 
 	// Should error about mismatch
 	if len(mt.errors) != 1 {
@@ -289,7 +328,7 @@ func TestSnapWithTitle_MismatchedContent(t *testing.T) {
 	}
 
 	// Should create new snapshot file
-	newSnap, err := files.ReadSnapshot("mismatch_title", "new")
+	newSnap, err := files.ReadSnapshot(files.SnapshotFileName("mismatch_title"), "new")
 	if err != nil {
 		t.Fatalf("failed to read new snapshot: %v", err)
 	}
@@ -313,7 +352,7 @@ func TestSnapWithTitle_HelperCalled(t *testing.T) {
 	setupTestDir(t)
 
 	mt := &mockT{name: "TestHelper"}
-	SnapWithTitle(mt, "helper_test", "TestHelper", "test.go", "v1", "content")
+	SnapWithTitle(mt, "helper_test", "TestHelper", "test.go", 0, "v1", "content")
 
 	if !mt.helperCalled {
 		t.Error("expected Helper() to be called")
@@ -337,7 +376,7 @@ func TestSnap_MultipleDifferentSnapshots(t *testing.T) {
 
 	// Verify all files exist
 	for _, title := range []string{"snap_one", "snap_two", "snap_three"} {
-		snapPath := filepath.Join("__snapshots__", title+".snap.new")
+		snapPath := filepath.Join("__snapshots__", files.SnapshotFileName(title)+".snap.new")
 		if _, err := os.Stat(snapPath); os.IsNotExist(err) {
 			t.Errorf("expected snapshot %s to exist", title)
 		}
@@ -351,7 +390,7 @@ func TestSnap_EmptyContent(t *testing.T) {
 	Snap(mt, "empty_test", "v1", "")
 
 	// Should create snapshot with empty content
-	snap, err := files.ReadSnapshot("empty_test", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("empty_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -370,7 +409,7 @@ line three`
 	mt := &mockT{name: "TestMultiline"}
 	Snap(mt, "multiline_test", "v1", content)
 
-	snap, err := files.ReadSnapshot("multiline_test", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("multiline_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -387,7 +426,7 @@ func TestSnap_SpecialCharacters(t *testing.T) {
 	mt := &mockT{name: "TestSpecial"}
 	Snap(mt, "special_test", "v1", content)
 
-	snap, err := files.ReadSnapshot("special_test", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("special_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -402,7 +441,7 @@ func TestSnap_VersionTracking(t *testing.T) {
 	mt := &mockT{name: "TestVersion"}
 	Snap(mt, "version_test", "v2", "content")
 
-	snap, err := files.ReadSnapshot("version_test", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("version_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -431,7 +470,7 @@ func TestSnap_UpdateAcceptedSnapshot(t *testing.T) {
 	Snap(mt, "update_test", "v2", "new version")
 
 	// Verify new snapshot was created
-	newSnap, err := files.ReadSnapshot("update_test", "new")
+	newSnap, err := files.ReadSnapshot(files.SnapshotFileName("update_test"), "new")
 	if err != nil {
 		t.Fatalf("failed to read new snapshot: %v", err)
 	}
@@ -444,7 +483,7 @@ func TestSnap_UpdateAcceptedSnapshot(t *testing.T) {
 	}
 
 	// Accepted snapshot should remain unchanged
-	acceptedSnap, err := files.ReadSnapshot("update_test", "snap")
+	acceptedSnap, err := files.ReadSnapshot(files.SnapshotFileName("update_test"), "snap")
 	if err != nil {
 		t.Fatalf("failed to read accepted snapshot: %v", err)
 	}
@@ -460,7 +499,7 @@ func TestSnap_WithSpacesInTitle(t *testing.T) {
 	Snap(mt, "test with spaces", "v1", "content")
 
 	// Should normalize title to filename
-	snap, err := files.ReadSnapshot("test with spaces", "new")
+	snap, err := files.ReadSnapshot(files.SnapshotFileName("test with spaces"), "new")
 	if err != nil {
 		t.Fatalf("failed to read snapshot: %v", err)
 	}
@@ -469,3 +508,74 @@ func TestSnap_WithSpacesInTitle(t *testing.T) {
 		t.Errorf("expected title to preserve spaces, got %q", snap.Title)
 	}
 }
+
+func TestSnap_RecordsVisitedName(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestVisited"}
+	Snap(mt, "visited title", "v1", "content")
+
+	if err := files.CompleteVisited(); err != nil {
+		t.Fatalf("failed to complete visited record: %v", err)
+	}
+	visited, err := files.ReadVisited()
+	if err != nil {
+		t.Fatalf("failed to read visited record: %v", err)
+	}
+
+	found := false
+	for _, name := range visited {
+		if name == "visited_title" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected %q to be recorded as visited, got %q", "visited_title", visited)
+	}
+}
+
+func TestSnap_ParallelTests(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "always")
+
+	const tests = 300
+
+	var wg sync.WaitGroup
+	mts := make([]*mockT, tests)
+	for i := range tests {
+		mts[i] = &mockT{name: fmt.Sprintf("TestParallel/case_%03d", i)}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Snap(mts[i], mts[i].name, "v1", fmt.Sprintf("content %d\n", i))
+			Snap(mts[i], "shared title", "v1", fmt.Sprintf("content %d\n", i))
+		}()
+	}
+	wg.Wait()
+
+	// Exactly one test may own the shared title; all others must fail loudly
+	owners := 0
+	for i, mt := range mts {
+		switch len(mt.errors) {
+		case 0:
+			owners++
+		case 1:
+			if !strings.Contains(mt.errors[0], "unique") {
+				t.Errorf("test %d: unexpected error %q", i, mt.errors[0])
+			}
+		default:
+			t.Errorf("test %d: unexpected errors %v", i, mt.errors)
+		}
+
+		snap, err := files.ReadAccepted(files.SnapshotFileName(mt.name))
+		if err != nil {
+			t.Fatalf("test %d: failed to read snapshot: %v", i, err)
+		}
+		if snap.Content != fmt.Sprintf("content %d\n", i) {
+			t.Errorf("test %d: unexpected content %q", i, snap.Content)
+		}
+	}
+	if owners != 1 {
+		t.Errorf("expected exactly one owner of the shared title, got %d", owners)
+	}
+}
@@ -0,0 +1,195 @@
+package snapshots
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// outcome is the result of comparing one snapshot.
+type outcome int
+
+const (
+	outcomeUnchanged outcome = iota
+	outcomeNew
+	outcomeChanged
+)
+
+// ReviewCommand is the command that reviews pending snapshots.
+const ReviewCommand = "go run github.com/ptdewey/shutter/cmd/shutter"
+
+// testSummary collects the outcomes of the snapshots taken by one test.
+type testSummary struct {
+	counts [3]int
+	// pending holds the files (or inline snapshot locations) awaiting review.
+	pending []string
+	// unwritten counts new or changed snapshots that were not saved for
+	// review because of the update mode.
+	unwritten int
+}
+
+// packageSummary aggregates the summaries of all finished tests.
+type packageSummary struct {
+	tests     int
+	counts    [3]int
+	pending   []string
+	unwritten int
+}
+
+var (
+	summaryMu sync.Mutex
+	// summaries holds the summary of every running test, by test name.
+	summaries = map[string]*testSummary{}
+	totals    packageSummary
+)
+
+// recordOutcome adds the outcome of a snapshot to the summary of its test.
+// pendingFile is the file written for review, if any. The summary is logged
+// when the test finishes.
+func recordOutcome(t T, o outcome, pendingFile string) {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+
+	name := t.Name()
+	summary, ok := summaries[name]
+	if !ok {
+		summary = &testSummary{}
+		summaries[name] = summary
+		t.Cleanup(func() {
+			t.Helper()
+			finishTest(t, name)
+		})
+	}
+
+	summary.counts[o]++
+	switch {
+	case pendingFile != "":
+		summary.pending = append(summary.pending, pendingFile)
+	case o != outcomeUnchanged:
+		if mode, err := CurrentUpdateMode(); err == nil && mode == UpdateNo {
+			summary.unwritten++
+		}
+	}
+}
+
+// finishTest logs the summary of a finished test and adds it to the totals.
+func finishTest(t T, name string) {
+	t.Helper()
+
+	summaryMu.Lock()
+	summary := summaries[name]
+	delete(summaries, name)
+	if summary != nil && summary.counts[outcomeNew]+summary.counts[outcomeChanged] > 0 {
+		totals.tests++
+		for i, n := range summary.counts {
+			totals.counts[i] += n
+		}
+		totals.pending = append(totals.pending, summary.pending...)
+		totals.unwritten += summary.unwritten
+	}
+	summaryMu.Unlock()
+
+	if summary == nil || summary.counts[outcomeNew]+summary.counts[outcomeChanged] == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("shutter: %d new, %d changed, %d unchanged snapshot(s)",
+		summary.counts[outcomeNew], summary.counts[outcomeChanged], summary.counts[outcomeUnchanged]))
+	for _, file := range summary.pending {
+		sb.WriteString("\n  " + file)
+	}
+	t.Log(sb.String())
+}
+
+// PackageSummary returns a summary of all new and changed snapshots of the
+// tests run so far, ending with the command to review them. It returns an
+// empty string if every snapshot matched.
+func PackageSummary() string {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+
+	if totals.tests == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("shutter: %d new and %d changed snapshot(s) in %d test(s)\n",
+		totals.counts[outcomeNew], totals.counts[outcomeChanged], totals.tests))
+
+	if len(totals.pending) > 0 {
+		sb.WriteString(fmt.Sprintf("%d snapshot(s) awaiting review:\n", len(totals.pending)))
+		for _, file := range totals.pending {
+			sb.WriteString("  " + file + "\n")
+		}
+		sb.WriteString("To review them, run from the module root:\n  " + reviewCommandLine() + "\n")
+	}
+	if totals.unwritten > 0 {
+		sb.WriteString(fmt.Sprintf("%d snapshot(s) were not written (%s=%s); rerun with %s=pending to review them\n",
+			totals.unwritten, UpdateEnvVar, UpdateNo, UpdateEnvVar))
+	}
+
+	return sb.String()
+}
+
+// resetSummaries forgets all recorded outcomes.
+func resetSummaries() {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	summaries = map[string]*testSummary{}
+	totals = packageSummary{}
+}
+
+// reviewCommandLine returns the shell command reviewing the snapshots of the
+// current package from the module root, with flags for any snapshot
+// directory or layout that differs from the default.
+func reviewCommandLine() string {
+	var prefix string
+	if dir, ok := packageDirFromModuleRoot(); ok && dir != "." {
+		prefix = "cd " + dir + " && "
+	}
+
+	cmd := ReviewCommand
+	if dir, err := files.SnapshotDir(); err == nil && dir != files.DefaultSnapshotDir {
+		cmd += " -dir " + quoteArg(filepath.ToSlash(dir))
+	}
+	if layout, err := files.CurrentLayout(); err == nil && layout != files.LayoutFlat {
+		cmd += " -layout " + layout.String()
+	}
+
+	return prefix + cmd + " review"
+}
+
+// packageDirFromModuleRoot returns the working directory, which go test sets
+// to the package directory, relative to the module root.
+func packageDirFromModuleRoot() (string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", false
+	}
+
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			rel, err := filepath.Rel(dir, cwd)
+			if err != nil {
+				return "", false
+			}
+			return quoteArg(filepath.ToSlash(rel)), true
+		}
+		if filepath.Dir(dir) == dir {
+			return "", false
+		}
+	}
+}
+
+// quoteArg quotes s for a POSIX shell if it contains special characters.
+func quoteArg(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`&|;<>()*?[]#~!{}") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,114 @@
+package snapshots
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func TestSummary_LoggedWhenTestFinishes(t *testing.T) {
+	setupTestDir(t)
+	resetSummaries()
+	t.Cleanup(resetSummaries)
+	saveAccepted(t, "same", "content")
+	saveAccepted(t, "changed", "old")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "same", "v1", "content")
+	Snap(mt, "changed", "v1", "new")
+	Snap(mt, "added", "v1", "content")
+
+	if len(mt.logs) != 0 {
+		t.Fatalf("expected no summary before the test finished, got %v", mt.logs)
+	}
+	mt.runCleanups()
+
+	if len(mt.logs) != 1 {
+		t.Fatalf("expected one summary log, got %v", mt.logs)
+	}
+	for _, want := range []string{
+		"1 new, 1 changed, 1 unchanged",
+		"__snapshots__/changed.snap.new",
+		"__snapshots__/added.snap.new",
+	} {
+		if !strings.Contains(mt.logs[0], want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, mt.logs[0])
+		}
+	}
+}
+
+func TestSummary_NothingLoggedWhenAllMatch(t *testing.T) {
+	setupTestDir(t)
+	resetSummaries()
+	t.Cleanup(resetSummaries)
+	saveAccepted(t, "same", "content")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "same", "v1", "content")
+	mt.runCleanups()
+
+	if len(mt.logs) != 0 {
+		t.Errorf("expected no summary, got %v", mt.logs)
+	}
+	if summary := PackageSummary(); summary != "" {
+		t.Errorf("expected an empty package summary, got:\n%s", summary)
+	}
+}
+
+func TestPackageSummary(t *testing.T) {
+	setupTestDir(t)
+	resetSummaries()
+	t.Cleanup(resetSummaries)
+
+	first := &mockT{name: "TestFirst"}
+	Snap(first, "first", "v1", "content")
+	first.runCleanups()
+
+	second := &mockT{name: "TestSecond"}
+	Snap(second, "second", "v1", "content")
+	second.runCleanups()
+
+	summary := PackageSummary()
+	for _, want := range []string{
+		"2 new and 0 changed snapshot(s) in 2 test(s)",
+		"__snapshots__/first.snap.new",
+		"__snapshots__/second.snap.new",
+		ReviewCommand + " review",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+}
+
+func TestPackageSummary_UpdateNo(t *testing.T) {
+	setupTestDir(t)
+	resetSummaries()
+	t.Cleanup(resetSummaries)
+	t.Setenv(UpdateEnvVar, "no")
+
+	mt := &mockT{name: "TestExample"}
+	Snap(mt, "unwritten", "v1", "content")
+	mt.runCleanups()
+
+	summary := PackageSummary()
+	if !strings.Contains(summary, UpdateEnvVar+"=pending") || strings.Contains(summary, ReviewCommand) {
+		t.Errorf("expected a hint to rerun in pending mode, got:\n%s", summary)
+	}
+}
+
+func TestReviewCommandLine_IncludesConfiguration(t *testing.T) {
+	setupTestDir(t)
+	files.SetSnapshotDir("testdata/my snaps")
+	files.SetLayout(files.LayoutNested)
+	t.Cleanup(func() {
+		files.SetSnapshotDir("")
+		files.SetLayout(files.LayoutFlat)
+	})
+
+	want := ReviewCommand + " -dir 'testdata/my snaps' -layout nested review"
+	if got := reviewCommandLine(); got != want {
+		t.Errorf("reviewCommandLine() = %q, want %q", got, want)
+	}
+}
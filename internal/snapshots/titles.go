@@ -0,0 +1,68 @@
+package snapshots
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+// titleOwner records which test first used a snapshot file in this process.
+type titleOwner struct {
+	t     T
+	test  string
+	title string
+	count int
+}
+
+var (
+	titlesMu sync.Mutex
+	// titles maps snapshot names to the test that owns them. Since each
+	// package's tests run in their own process, this tracks a single package.
+	titles = map[string]*titleOwner{}
+)
+
+// claimTitle registers title as used by t and returns the title to store the
+// snapshot under. Repeated uses within one test are told apart with a counter
+// suffix, following the naming Go uses for duplicate subtests. Reusing a title
+// from a different test is an error if both would write the same file, which
+// depends on the configured layout.
+func claimTitle(t T, testName, fileName, title string) (string, error) {
+	key, err := files.SnapshotName(&files.Snapshot{Title: title, Test: testName, FileName: fileName})
+	if err != nil {
+		return "", err
+	}
+
+	titlesMu.Lock()
+	defer titlesMu.Unlock()
+
+	owner, ok := titles[key]
+	switch {
+	case !ok:
+		titles[key] = &titleOwner{t: t, test: testName, title: title}
+		return title, nil
+	case owner.t == t:
+		owner.count++
+		return fmt.Sprintf("%s#%02d", title, owner.count), nil
+	case owner.test == testName:
+		// The same test is running again, e.g. with -count
+		owner.t = t
+		owner.title = title
+		owner.count = 0
+		return title, nil
+	default:
+		if owner.title != title {
+			return "", fmt.Errorf("snapshot title %q in %s maps to the same file as %q in %s; use distinct titles",
+				title, testName, owner.title, owner.test)
+		}
+		return "", fmt.Errorf("snapshot title %q is used by both %s and %s; snapshot titles must be unique within a package",
+			title, owner.test, testName)
+	}
+}
+
+// resetTitles forgets all claimed titles.
+func resetTitles() {
+	titlesMu.Lock()
+	defer titlesMu.Unlock()
+	titles = map[string]*titleOwner{}
+}
@@ -0,0 +1,88 @@
+package snapshots
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnap_DuplicateTitleAcrossTests(t *testing.T) {
+	setupTestDir(t)
+
+	first := &mockT{name: "TestFirst"}
+	Snap(first, "user", "v1", "first content")
+
+	second := &mockT{name: "TestSecond"}
+	Snap(second, "user", "v1", "second content")
+
+	if len(second.errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(second.errors), second.errors)
+	}
+	for _, want := range []string{`"user"`, "TestFirst", "TestSecond"} {
+		if !strings.Contains(second.errors[0], want) {
+			t.Errorf("expected error to mention %s, got: %s", want, second.errors[0])
+		}
+	}
+
+	snap, err := os.ReadFile(filepath.Join("__snapshots__", "user.snap.new"))
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+	}
+	if !strings.Contains(string(snap), "first content") {
+		t.Error("second test must not overwrite the first test's snapshot")
+	}
+}
+
+func TestSnap_DuplicateTitleDifferentCase(t *testing.T) {
+	setupTestDir(t)
+
+	Snap(&mockT{name: "TestFirst"}, "User", "v1", "content")
+
+	second := &mockT{name: "TestSecond"}
+	Snap(second, "user", "v1", "content")
+
+	if len(second.errors) != 1 || !strings.Contains(second.errors[0], "same file") {
+		t.Errorf("expected collision error, got: %v", second.errors)
+	}
+}
+
+func TestSnap_DuplicateTitleWithinTest(t *testing.T) {
+	setupTestDir(t)
+
+	mt := &mockT{name: "TestRepeated"}
+	Snap(mt, "repeated", "v1", "one")
+	Snap(mt, "repeated", "v1", "two")
+	Snap(mt, "repeated", "v1", "three")
+
+	for _, name := range []string{"repeated.snap.new", "repeated#01.snap.new", "repeated#02.snap.new"} {
+		if _, err := os.Stat(filepath.Join("__snapshots__", name)); err != nil {
+			t.Errorf("expected %s to exist: %v", name, err)
+		}
+	}
+
+	for _, e := range mt.errors {
+		if !strings.Contains(e, "new snapshot created") {
+			t.Errorf("unexpected error: %s", e)
+		}
+	}
+}
+
+func TestSnap_DuplicateTitleRerun(t *testing.T) {
+	setupTestDir(t)
+
+	// Simulates go test -count=2, where the same test runs with a new T
+	Snap(&mockT{name: "TestRerun"}, "rerun", "v1", "content")
+
+	second := &mockT{name: "TestRerun"}
+	Snap(second, "rerun", "v1", "content")
+
+	if _, err := os.Stat(filepath.Join("__snapshots__", "rerun#01.snap.new")); err == nil {
+		t.Error("rerunning a test should not produce counter-suffixed snapshots")
+	}
+	for _, e := range second.errors {
+		if strings.Contains(e, "unique") {
+			t.Errorf("rerunning a test should not report duplicates: %s", e)
+		}
+	}
+}
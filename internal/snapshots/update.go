@@ -0,0 +1,137 @@
+package snapshots
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// UpdateMode controls what happens when a snapshot is new or does not match
+// the accepted snapshot on disk.
+type UpdateMode int
+
+const (
+	// UpdateAuto behaves like UpdateNo when running in CI and like
+	// UpdatePending everywhere else.
+	UpdateAuto UpdateMode = iota
+	// UpdatePending writes new and changed snapshots to .snap.new files
+	// and fails the test so they can be reviewed.
+	UpdatePending
+	// UpdateAlways overwrites accepted snapshots in place and passes.
+	UpdateAlways
+	// UpdateNew accepts snapshots that have never been seen before and
+	// treats changed snapshots like UpdatePending.
+	UpdateNew
+	// UpdateNo never writes anything and fails on new or changed snapshots.
+	UpdateNo
+)
+
+// UpdateEnvVar is the environment variable used to select the update mode.
+const UpdateEnvVar = "SHUTTER_UPDATE"
+
+// ciEnvVars are environment variables set by common CI providers.
+var ciEnvVars = []string{
+	"CI", "GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "CIRCLECI",
+	"JENKINS_URL", "TEAMCITY_VERSION", "TF_BUILD", "TRAVIS",
+}
+
+var (
+	updateMu   sync.RWMutex
+	updateMode UpdateMode
+	updateSet  bool
+)
+
+func (m UpdateMode) String() string {
+	switch m {
+	case UpdateAuto:
+		return "auto"
+	case UpdatePending:
+		return "pending"
+	case UpdateAlways:
+		return "always"
+	case UpdateNew:
+		return "new"
+	case UpdateNo:
+		return "no"
+	default:
+		return fmt.Sprintf("UpdateMode(%d)", int(m))
+	}
+}
+
+// ParseUpdateMode parses the textual form of an update mode as accepted by
+// the SHUTTER_UPDATE environment variable.
+func ParseUpdateMode(s string) (UpdateMode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return UpdateAuto, nil
+	case "pending":
+		return UpdatePending, nil
+	case "always", "overwrite":
+		return UpdateAlways, nil
+	case "new", "unseen":
+		return UpdateNew, nil
+	case "no", "none":
+		return UpdateNo, nil
+	default:
+		return UpdateAuto, fmt.Errorf("invalid %s value %q (expected auto, pending, always, new or no)", UpdateEnvVar, s)
+	}
+}
+
+// SetUpdateMode overrides the update mode for the current process. A mode set
+// here takes precedence over the SHUTTER_UPDATE environment variable.
+func SetUpdateMode(mode UpdateMode) {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	updateMode = mode
+	updateSet = true
+}
+
+// resetUpdateMode clears a mode set with SetUpdateMode.
+func resetUpdateMode() {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+	updateMode = UpdateAuto
+	updateSet = false
+}
+
+// CurrentUpdateMode returns the effective update mode, resolving UpdateAuto
+// based on whether a CI environment is detected.
+func CurrentUpdateMode() (UpdateMode, error) {
+	updateMu.RLock()
+	mode, set := updateMode, updateSet
+	updateMu.RUnlock()
+
+	if !set {
+		var err error
+		mode, err = ParseUpdateMode(os.Getenv(UpdateEnvVar))
+		if err != nil {
+			return UpdateNo, err
+		}
+	}
+
+	if mode == UpdateAuto {
+		if isCI() {
+			return UpdateNo, nil
+		}
+		return UpdatePending, nil
+	}
+
+	return mode, nil
+}
+
+// isCI reports whether the process appears to be running in a CI environment.
+func isCI() bool {
+	for _, name := range ciEnvVars {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "", "0", "false", "no":
+			continue
+		}
+		return true
+	}
+	return false
+}
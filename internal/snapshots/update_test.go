@@ -0,0 +1,216 @@
+package snapshots
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func saveAccepted(t *testing.T, title, content string) {
+	t.Helper()
+
+	accepted := &files.Snapshot{
+		Title:    title,
+		Test:     "TestExample",
+		FileName: "test.go",
+		Content:  content,
+		Version:  "v1",
+	}
+	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestParseUpdateMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    UpdateMode
+		wantErr bool
+	}{
+		{"", UpdateAuto, false},
+		{"auto", UpdateAuto, false},
+		{"pending", UpdatePending, false},
+		{"always", UpdateAlways, false},
+		{"ALWAYS", UpdateAlways, false},
+		{"new", UpdateNew, false},
+		{"no", UpdateNo, false},
+		{"sometimes", UpdateAuto, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseUpdateMode(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseUpdateMode(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseUpdateMode(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentUpdateMode_AutoResolution(t *testing.T) {
+	for _, name := range ciEnvVars {
+		t.Setenv(name, "")
+	}
+	t.Setenv(UpdateEnvVar, "auto")
+
+	mode, err := CurrentUpdateMode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode != UpdatePending {
+		t.Errorf("expected pending outside CI, got %v", mode)
+	}
+
+	t.Setenv("CI", "true")
+	mode, err = CurrentUpdateMode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode != UpdateNo {
+		t.Errorf("expected no in CI, got %v", mode)
+	}
+
+	t.Setenv("CI", "false")
+	mode, _ = CurrentUpdateMode()
+	if mode != UpdatePending {
+		t.Errorf("expected CI=false to be ignored, got %v", mode)
+	}
+}
+
+func TestCurrentUpdateMode_InvalidEnv(t *testing.T) {
+	t.Setenv(UpdateEnvVar, "bogus")
+
+	if _, err := CurrentUpdateMode(); err == nil {
+		t.Error("expected error for invalid update mode")
+	}
+}
+
+func TestSetUpdateMode_OverridesEnv(t *testing.T) {
+	t.Setenv(UpdateEnvVar, "no")
+	SetUpdateMode(UpdateAlways)
+	t.Cleanup(resetUpdateMode)
+
+	mode, err := CurrentUpdateMode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mode != UpdateAlways {
+		t.Errorf("expected SetUpdateMode to take precedence, got %v", mode)
+	}
+}
+
+func TestUpdateAlways_OverwritesMismatch(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "always")
+	saveAccepted(t, "always_test", "old content")
+
+	mt := &mockT{name: "TestAlways"}
+	Snap(mt, "always_test", "v1", "new content")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+
+	accepted, err := files.ReadAccepted(files.SnapshotFileName("always_test"))
+	if err != nil {
+		t.Fatalf("failed to read accepted snapshot: %v", err)
+	}
+	if accepted.Content != "new content" {
+		t.Errorf("expected accepted snapshot to be overwritten, got %q", accepted.Content)
+	}
+	if fileExists(filepath.Join("__snapshots__", files.SnapshotFileName("always_test")+".snap.new")) {
+		t.Error("expected no .snap.new file to be written")
+	}
+}
+
+func TestUpdateAlways_AcceptsNew(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "always")
+
+	mt := &mockT{name: "TestAlways"}
+	Snap(mt, "always_new", "v1", "content")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if !fileExists(filepath.Join("__snapshots__", files.SnapshotFileName("always_new")+".snap")) {
+		t.Error("expected accepted snapshot to be written")
+	}
+}
+
+func TestUpdateNew_AcceptsOnlyUnseen(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "new")
+	saveAccepted(t, "seen_test", "old content")
+
+	mt := &mockT{name: "TestNew"}
+	Snap(mt, "unseen_test", "v1", "content")
+	Snap(mt, "seen_test", "v1", "changed content")
+
+	if len(mt.errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(mt.errors), mt.errors)
+	}
+	if !strings.Contains(mt.errors[0], "snapshot mismatch") {
+		t.Errorf("expected mismatch error, got: %s", mt.errors[0])
+	}
+	if !fileExists(filepath.Join("__snapshots__", files.SnapshotFileName("unseen_test")+".snap")) {
+		t.Error("expected unseen snapshot to be accepted")
+	}
+	if !fileExists(filepath.Join("__snapshots__", files.SnapshotFileName("seen_test")+".snap.new")) {
+		t.Error("expected changed snapshot to be written as .snap.new")
+	}
+}
+
+func TestUpdateNo_WritesNothing(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "no")
+	saveAccepted(t, "no_mismatch", "old content")
+
+	mt := &mockT{name: "TestNo"}
+	Snap(mt, "no_new", "v1", "content")
+	Snap(mt, "no_mismatch", "v1", "changed content")
+
+	if len(mt.errors) != 2 {
+		t.Fatalf("expected 2 errors, got %d: %v", len(mt.errors), mt.errors)
+	}
+	for _, name := range []string{
+		files.SnapshotFileName("no_new") + ".snap",
+		files.SnapshotFileName("no_new") + ".snap.new",
+		files.SnapshotFileName("no_mismatch") + ".snap.new",
+	} {
+		if fileExists(filepath.Join("__snapshots__", name)) {
+			t.Errorf("expected %s not to be written", name)
+		}
+	}
+}
+
+func TestUpdateNo_KeepsStalePendingSnapshot(t *testing.T) {
+	setupTestDir(t)
+	saveAccepted(t, "no_stale", "content")
+	snap := &files.Snapshot{Title: "no_stale", Test: "TestNo", Content: "changed content"}
+	if err := files.SaveSnapshot(snap, "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(UpdateEnvVar, "no")
+	mt := &mockT{name: "TestNo"}
+	Snap(mt, "no_stale", "v1", "content")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if !fileExists(filepath.Join("__snapshots__", files.SnapshotFileName("no_stale")+".snap.new")) {
+		t.Error("expected the pending snapshot to be left alone")
+	}
+}
@@ -0,0 +1,83 @@
+package snapshots
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter/internal/files"
+)
+
+func saveAcceptedVersion(t *testing.T, title, version, content string) {
+	t.Helper()
+
+	accepted := &files.Snapshot{
+		Title:    title,
+		Test:     "TestExample",
+		FileName: "test.go",
+		Content:  content,
+		Version:  version,
+	}
+	if err := files.SaveSnapshot(accepted, "accepted"); err != nil {
+		t.Fatalf("failed to save accepted snapshot: %v", err)
+	}
+}
+
+func TestSnap_OlderFormatLogsMigrateHint(t *testing.T) {
+	setupTestDir(t)
+	saveAcceptedVersion(t, "older_format", "0.1.0", "content")
+
+	mt := &mockT{name: "TestOlder"}
+	Snap(mt, "older_format", "0.2.0", "content")
+
+	if len(mt.errors) != 0 {
+		t.Errorf("expected no errors, got %v", mt.errors)
+	}
+	if len(mt.logs) != 1 || !strings.Contains(mt.logs[0], "shutter migrate") {
+		t.Errorf("expected a migrate hint, got %v", mt.logs)
+	}
+}
+
+func TestSnap_NewerFormatFailsWithoutOverwriting(t *testing.T) {
+	setupTestDir(t)
+	t.Setenv(UpdateEnvVar, "always")
+	saveAcceptedVersion(t, "newer_format", "9.0.0", "old content")
+
+	mt := &mockT{name: "TestNewer"}
+	Snap(mt, "newer_format", "0.2.0", "new content")
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "upgrade shutter") {
+		t.Fatalf("expected an upgrade error, got %v", mt.errors)
+	}
+
+	accepted, err := files.ReadAccepted(files.SnapshotFileName("newer_format"))
+	if err != nil {
+		t.Fatalf("failed to read accepted snapshot: %v", err)
+	}
+	if accepted.Content != "old content" {
+		t.Errorf("expected accepted snapshot to be left alone, got %q", accepted.Content)
+	}
+}
+
+func TestSnap_MalformedAcceptedIsReported(t *testing.T) {
+	setupTestDir(t)
+
+	path := filepath.Join("__snapshots__", "malformed.snap")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("---\ntitle: malformed\nno separator\n---\ncontent"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mt := &mockT{name: "TestMalformed"}
+	Snap(mt, "malformed", "0.2.0", "content")
+
+	if len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "malformed.snap:3") {
+		t.Fatalf("expected a parse error with location, got %v", mt.errors)
+	}
+	if fileExists(path + ".new") {
+		t.Error("expected no .snap.new file to be written")
+	}
+}
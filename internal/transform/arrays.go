@@ -0,0 +1,130 @@
+package transform
+
+import (
+	"cmp"
+	"encoding/json"
+	"slices"
+)
+
+// ArrayRule drops and sorts the elements of the arrays at matching paths.
+type ArrayRule struct {
+	Path *PathPattern
+	// Drop removes the elements for which it returns true, if set. It sees
+	// elements decoded like redaction values; see Redaction.Replace.
+	Drop func(value any) bool
+	// Sort sorts the elements, by the value of SortKey if set, or else by
+	// their canonical JSON encoding.
+	Sort    bool
+	SortKey string
+}
+
+// walkArrays applies rules to every array whose path they match, innermost
+// arrays first so that sorting by canonical value sees sorted children.
+func walkArrays(data any, rules []ArrayRule, path []PathElement) any {
+	switch v := data.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			result[key] = walkArrays(value, rules, appendPath(path, PathElement{Key: key}))
+		}
+		return result
+	case *orderedObject:
+		result := &orderedObject{values: make(map[string]any, len(v.keys))}
+		for _, key := range v.keys {
+			result.set(key, walkArrays(v.values[key], rules, appendPath(path, PathElement{Key: key})))
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = walkArrays(item, rules, appendPath(path, PathElement{Index: i, IsIndex: true}))
+		}
+		for _, rule := range rules {
+			if rule.Path.ShouldIgnorePath(path, "") {
+				result = applyArrayRule(result, rule)
+			}
+		}
+		return result
+	default:
+		return data
+	}
+}
+
+// applyArrayRule drops and then sorts the elements of an array.
+func applyArrayRule(elements []any, rule ArrayRule) []any {
+	if rule.Drop != nil {
+		elements = slices.DeleteFunc(elements, func(element any) bool {
+			return rule.Drop(plainValue(element, nil))
+		})
+	}
+	if rule.Sort {
+		slices.SortStableFunc(elements, func(a, b any) int {
+			if rule.SortKey != "" {
+				return compareValues(fieldValue(a, rule.SortKey), fieldValue(b, rule.SortKey))
+			}
+			return cmp.Compare(canonicalJSON(a), canonicalJSON(b))
+		})
+	}
+	return elements
+}
+
+// fieldValue returns the value of key in an object, or nil if value is not an
+// object or has no such key.
+func fieldValue(value any, key string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return v[key]
+	case *orderedObject:
+		return v.values[key]
+	}
+	return nil
+}
+
+// compareValues orders two JSON values: numbers numerically and strings
+// lexically, with anything else ordered by its canonical encoding. Missing
+// and null values sort first.
+func compareValues(a, b any) int {
+	if a == nil || b == nil {
+		return cmp.Compare(boolRank(a != nil), boolRank(b != nil))
+	}
+	if na, ok := number(a); ok {
+		if nb, ok := number(b); ok {
+			return cmp.Compare(na, nb)
+		}
+	}
+	if sa, ok := a.(string); ok {
+		if sb, ok := b.(string); ok {
+			return cmp.Compare(sa, sb)
+		}
+	}
+	return cmp.Compare(canonicalJSON(a), canonicalJSON(b))
+}
+
+// boolRank orders false before true.
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// number returns a JSON number as a float64.
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// canonicalJSON encodes a value with sorted object keys.
+func canonicalJSON(value any) string {
+	data, err := json.Marshal(plainValue(value, nil))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
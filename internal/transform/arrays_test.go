@@ -0,0 +1,130 @@
+package transform
+
+import "testing"
+
+func mustParsePath(t *testing.T, expr string) *PathPattern {
+	t.Helper()
+	pattern, err := ParsePath(expr)
+	if err != nil {
+		t.Fatalf("ParsePath(%q) failed: %v", expr, err)
+	}
+	return pattern
+}
+
+func TestTransformJSON_SortArrays(t *testing.T) {
+	config := &Config{Arrays: []ArrayRule{
+		{Path: mustParsePath(t, "$"), Sort: true},
+		{Path: mustParsePath(t, "$[*].tags"), Sort: true},
+	}}
+
+	result, err := TransformJSON(`[{"tags": ["b", "a"], "n": 2}, {"tags": ["c"], "n": 1}, 10, 9]`, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Canonical JSON ordering: numbers before objects, inner arrays sorted first
+	expected := `[
+  10,
+  9,
+  {
+    "n": 1,
+    "tags": [
+      "c"
+    ]
+  },
+  {
+    "n": 2,
+    "tags": [
+      "a",
+      "b"
+    ]
+  }
+]`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_SortArrayByKey(t *testing.T) {
+	config := &Config{
+		Faithful:         true,
+		PreserveKeyOrder: true,
+		Arrays: []ArrayRule{
+			{Path: mustParsePath(t, "$.users"), Sort: true, SortKey: "id"},
+			{Path: mustParsePath(t, "$.names"), Sort: true, SortKey: "name"},
+		},
+	}
+
+	input := `{
+		"users": [{"id": 10, "v": "a"}, {"id": 9}, {"v": "no id"}, {"id": 10, "v": "b"}],
+		"names": [{"name": "bob"}, {"name": "Ann"}, {"name": "ann"}]
+	}`
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Numbers sort numerically, missing keys first and ties stay in order
+	expected := `{
+  "users": [
+    {
+      "v": "no id"
+    },
+    {
+      "id": 9
+    },
+    {
+      "id": 10,
+      "v": "a"
+    },
+    {
+      "id": 10,
+      "v": "b"
+    }
+  ],
+  "names": [
+    {
+      "name": "Ann"
+    },
+    {
+      "name": "ann"
+    },
+    {
+      "name": "bob"
+    }
+  ]
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_DropElements(t *testing.T) {
+	config := &Config{Arrays: []ArrayRule{{
+		Path: mustParsePath(t, "$..events"),
+		Drop: func(value any) bool {
+			event, _ := value.(map[string]any)
+			return event["type"] == "heartbeat"
+		},
+	}}}
+
+	input := `{"events": [{"type": "heartbeat"}, {"type": "login"}], "nested": {"events": [{"type": "heartbeat"}]}}`
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "events": [
+    {
+      "type": "login"
+    }
+  ],
+  "nested": {
+    "events": []
+  }
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
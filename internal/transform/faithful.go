@@ -0,0 +1,204 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// orderedObject is a JSON object that remembers the order of its keys.
+type orderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// set adds or replaces a key, keeping the position of an existing key like
+// encoding/json keeps the last value of duplicate keys.
+func (o *orderedObject) set(key string, value any) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// MarshalJSON encodes the object with its keys in order.
+func (o *orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeFaithful decodes JSON data, keeping numbers as json.Number if
+// config.Faithful is set and objects as *orderedObject if
+// config.PreserveKeyOrder is set.
+func decodeFaithful(data []byte, config *Config) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if config.Faithful {
+		dec.UseNumber()
+	}
+
+	var value any
+	var err error
+	if config.PreserveKeyOrder {
+		value, err = decodeOrdered(dec)
+	} else {
+		err = dec.Decode(&value)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Like json.Unmarshal, reject trailing data
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid character after top-level value")
+	}
+	return value, nil
+}
+
+// decodeOrdered decodes the next JSON value from dec, keeping the key order
+// of objects.
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		obj := &orderedObject{values: make(map[string]any)}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj.set(keyTok.(string), value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := []any{}
+		for dec.More() {
+			value, err := decodeOrdered(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	default:
+		return tok, nil
+	}
+}
+
+// writeIndented writes data as JSON indented like json.MarshalIndent with a
+// two-space indent. Numbers decoded as json.Number are written verbatim, and
+// <, > and & are only escaped if escapeHTML is set.
+func writeIndented(sb *strings.Builder, data any, indent string, escapeHTML bool) error {
+	inner := indent + "  "
+
+	switch v := data.(type) {
+	case *orderedObject:
+		return writeObject(sb, v.keys, v.values, indent, escapeHTML)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		return writeObject(sb, keys, v, indent, escapeHTML)
+	case []any:
+		if len(v) == 0 {
+			sb.WriteString("[]")
+			return nil
+		}
+		sb.WriteString("[\n")
+		for i, item := range v {
+			sb.WriteString(inner)
+			if err := writeIndented(sb, item, inner, escapeHTML); err != nil {
+				return err
+			}
+			if i < len(v)-1 {
+				sb.WriteString(",")
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString(indent + "]")
+		return nil
+	case json.Number:
+		sb.WriteString(v.String())
+		return nil
+	default:
+		return writeScalar(sb, v, escapeHTML)
+	}
+}
+
+// writeObject writes the given keys of an object in order.
+func writeObject(sb *strings.Builder, keys []string, values map[string]any, indent string, escapeHTML bool) error {
+	if len(keys) == 0 {
+		sb.WriteString("{}")
+		return nil
+	}
+
+	inner := indent + "  "
+	sb.WriteString("{\n")
+	for i, key := range keys {
+		sb.WriteString(inner)
+		if err := writeScalar(sb, key, escapeHTML); err != nil {
+			return err
+		}
+		sb.WriteString(": ")
+		if err := writeIndented(sb, values[key], inner, escapeHTML); err != nil {
+			return err
+		}
+		if i < len(keys)-1 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString(indent + "}")
+	return nil
+}
+
+// writeScalar writes a string, number, boolean or null.
+func writeScalar(sb *strings.Builder, value any, escapeHTML bool) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(escapeHTML)
+	if err := enc.Encode(value); err != nil {
+		return err
+	}
+	sb.WriteString(strings.TrimSuffix(buf.String(), "\n"))
+	return nil
+}
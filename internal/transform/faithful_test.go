@@ -0,0 +1,111 @@
+package transform
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTransformJSON_Faithful(t *testing.T) {
+	input := `{"id": 9007199254740993, "ratio": 1.50, "big": 1e400, "html": "<b>&</b>", "b": 1, "a": 2}`
+
+	result, err := TransformJSON(input, &Config{Faithful: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "a": 2,
+  "b": 1,
+  "big": 1e400,
+  "html": "<b>&</b>",
+  "id": 9007199254740993,
+  "ratio": 1.50
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+
+	// The default mode normalizes numbers and escapes HTML
+	result, err = TransformJSON(`{"id": 9007199254740993, "html": "<b>"}`, &Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(result, "9007199254740992") || !strings.Contains(result, `\u003cb\u003e`) {
+		t.Errorf("expected lossy numbers and escaped HTML without Faithful, got:\n%s", result)
+	}
+}
+
+func TestTransformJSON_PreserveKeyOrder(t *testing.T) {
+	input := `{"z": {"y": 1, "x": [{"b": true, "a": null}]}, "a": "first", "empty": {}, "none": []}`
+	config := &Config{
+		PreserveKeyOrder: true,
+		Ignore: []IgnorePattern{&mockIgnorePattern{
+			fn: func(key, value string) bool { return key == "a" && value == "first" },
+		}},
+	}
+
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "z": {
+    "y": 1,
+    "x": [
+      {
+        "b": true,
+        "a": null
+      }
+    ]
+  },
+  "empty": {},
+  "none": []
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_FaithfulMatchesDefaultFormatting(t *testing.T) {
+	input := `{"list": [1, "two", {"three": [3]}, [], {}], "nested": {"ok": false, "nothing": null}}`
+
+	plain, err := TransformJSON(input, &Config{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	faithful, err := TransformJSON(input, &Config{Faithful: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if plain != faithful {
+		t.Errorf("expected identical output for plain JSON:\n%s\nvs:\n%s", plain, faithful)
+	}
+}
+
+func TestTransformJSON_FaithfulInvalidJSON(t *testing.T) {
+	inputs := []string{`{"a": 1} {"b": 2}`, `{"a": `, `[1, 2`, ``}
+
+	for _, input := range inputs {
+		for _, config := range []*Config{{Faithful: true}, {PreserveKeyOrder: true}} {
+			if _, err := TransformJSON(input, config); err == nil {
+				t.Errorf("expected an error for %q with %+v", input, config)
+			}
+		}
+	}
+}
+
+func TestValueToString_OrderedValues(t *testing.T) {
+	obj := &orderedObject{values: map[string]any{}}
+	obj.set("b", json.Number("10000000000000000001"))
+	obj.set("a", "<x>")
+
+	if got := valueToString(obj); got != `{"b":10000000000000000001,"a":"\u003cx\u003e"}` {
+		t.Errorf("valueToString() = %s", got)
+	}
+	if got := valueToString(json.Number("1.50")); got != "1.50" {
+		t.Errorf("valueToString() = %s", got)
+	}
+}
@@ -0,0 +1,49 @@
+package transform
+
+// walkInclude keeps the values whose path matches one of patterns, together
+// with the objects and arrays on the way to them. Containers with nothing to
+// keep are removed from their parents, except for the document itself, which
+// is left empty.
+func walkInclude(data any, patterns []*PathPattern, path []PathElement) any {
+	result, _ := prune(data, patterns, path)
+	return result
+}
+
+// prune returns the included parts of the value at path, and whether
+// anything in it is included.
+func prune(data any, patterns []*PathPattern, path []PathElement) (any, bool) {
+	for _, pattern := range patterns {
+		if pattern.ShouldIgnorePath(path, "") {
+			return data, true
+		}
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		result := make(map[string]any)
+		for key, value := range v {
+			if kept, ok := prune(value, patterns, appendPath(path, PathElement{Key: key})); ok {
+				result[key] = kept
+			}
+		}
+		return result, len(result) > 0
+	case *orderedObject:
+		result := &orderedObject{values: make(map[string]any)}
+		for _, key := range v.keys {
+			if kept, ok := prune(v.values[key], patterns, appendPath(path, PathElement{Key: key})); ok {
+				result.set(key, kept)
+			}
+		}
+		return result, len(result.keys) > 0
+	case []any:
+		result := make([]any, 0, len(v))
+		for i, item := range v {
+			if kept, ok := prune(item, patterns, appendPath(path, PathElement{Index: i, IsIndex: true})); ok {
+				result = append(result, kept)
+			}
+		}
+		return result, len(result) > 0
+	default:
+		return nil, false
+	}
+}
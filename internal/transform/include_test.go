@@ -0,0 +1,99 @@
+package transform
+
+import "testing"
+
+func TestTransformJSON_Include(t *testing.T) {
+	config := &Config{
+		Include: []*PathPattern{
+			mustParsePath(t, "$.user.name"),
+			mustParsePath(t, "$.items[*].id"),
+			KeyPath("status"),
+		},
+		Ignore: []IgnorePattern{
+			&mockIgnorePattern{fn: func(key, value string) bool { return value == "hidden" }},
+		},
+	}
+
+	input := `{
+		"user": {"name": "Ann", "email": "ann@example.com"},
+		"items": [{"id": 1, "price": 5}, {"price": 7}, {"id": 3}],
+		"meta": {"status": "ok", "took": 12},
+		"debug": {"trace": [1, 2, 3]},
+		"status": "hidden"
+	}`
+	result, err := TransformJSON(input, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Elements with nothing included are dropped, and ignores apply to what
+	// is left
+	expected := `{
+  "items": [
+    {
+      "id": 1
+    },
+    {
+      "id": 3
+    }
+  ],
+  "meta": {
+    "status": "ok"
+  },
+  "user": {
+    "name": "Ann"
+  }
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_IncludeKeepsSubtrees(t *testing.T) {
+	config := &Config{
+		PreserveKeyOrder: true,
+		Include:          []*PathPattern{mustParsePath(t, "$.b")},
+	}
+
+	result, err := TransformJSON(`{"c": 1, "b": {"z": [1, {"y": 2}], "x": null}, "a": 3}`, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{
+  "b": {
+    "z": [
+      1,
+      {
+        "y": 2
+      }
+    ],
+    "x": null
+  }
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_IncludeNothingMatched(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1}`, `{}`},
+		{`[{"a": 1}]`, `[]`},
+		{`"text"`, `null`},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Include: []*PathPattern{KeyPath("missing")}}
+		result, err := TransformJSON(tt.input, config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result != tt.expected {
+			t.Errorf("TransformJSON(%s) = %s, expected %s", tt.input, result, tt.expected)
+		}
+	}
+}
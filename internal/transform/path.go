@@ -0,0 +1,192 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathElement is one step from the root of a JSON document to a value: an
+// object key or an array index.
+type PathElement struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+// PathIgnorePattern is an IgnorePattern that also sees where a value is.
+// Unlike key/value patterns, it can remove array elements.
+type PathIgnorePattern interface {
+	IgnorePattern
+	ShouldIgnorePath(path []PathElement, value string) bool
+}
+
+// segmentKind is the kind of a step in a path expression.
+type segmentKind int
+
+const (
+	segmentKey      segmentKind = iota // .name or ['name']
+	segmentIndex                       // [n]
+	segmentWildcard                    // .* or [*]
+	segmentDescent                     // .., any number of steps
+)
+
+type pathSegment struct {
+	kind  segmentKind
+	key   string
+	index int
+}
+
+// PathPattern matches values by a JSONPath-like expression.
+type PathPattern struct {
+	expr     string
+	segments []pathSegment
+}
+
+// ParsePath parses a JSONPath-like expression such as "$.users[*].created_at".
+// The expression starts with "$" for the document root, followed by any of:
+//
+//	.name or ['name']  an object key
+//	[n]                an array index
+//	.* or [*]          any key or index
+//	..name             name at any depth below
+func ParsePath(expr string) (*PathPattern, error) {
+	rest, ok := strings.CutPrefix(expr, "$")
+	if !ok {
+		return nil, fmt.Errorf("invalid path %q: must start with $", expr)
+	}
+
+	var segments []pathSegment
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segments = append(segments, pathSegment{kind: segmentDescent})
+			rest = rest[1:]
+			if strings.HasPrefix(rest, ".[") {
+				rest = rest[1:]
+			}
+		case strings.HasPrefix(rest, ".*"):
+			segments = append(segments, pathSegment{kind: segmentWildcard})
+			rest = rest[2:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("invalid path %q: empty key", expr)
+			}
+			segments = append(segments, pathSegment{kind: segmentKey, key: name})
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			segment, n, err := parseBracket(rest)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %v", expr, err)
+			}
+			segments = append(segments, segment)
+			rest = rest[n:]
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q", expr, rest[:1])
+		}
+	}
+
+	if len(segments) > 0 && segments[len(segments)-1].kind == segmentDescent {
+		return nil, fmt.Errorf("invalid path %q: must not end with ..", expr)
+	}
+
+	return &PathPattern{expr: expr, segments: segments}, nil
+}
+
+// KeyPath returns a pattern matching the values of every object key named
+// key, at any depth, like "$..key".
+func KeyPath(key string) *PathPattern {
+	return &PathPattern{
+		expr: "$..['" + key + "']",
+		segments: []pathSegment{
+			{kind: segmentDescent},
+			{kind: segmentKey, key: key},
+		},
+	}
+}
+
+// parseBracket parses a bracketed step at the start of s, returning it and
+// its length.
+func parseBracket(s string) (pathSegment, int, error) {
+	if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+		quote := s[1]
+		end := strings.IndexByte(s[2:], quote)
+		if end < 0 || !strings.HasPrefix(s[2+end+1:], "]") {
+			return pathSegment{}, 0, fmt.Errorf("unterminated quoted key")
+		}
+		return pathSegment{kind: segmentKey, key: s[2 : 2+end]}, 2 + end + 2, nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathSegment{}, 0, fmt.Errorf("missing ]")
+	}
+	inner := s[1:end]
+	if inner == "*" {
+		return pathSegment{kind: segmentWildcard}, end + 1, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil || index < 0 {
+		return pathSegment{}, 0, fmt.Errorf("invalid array index %q", inner)
+	}
+	return pathSegment{kind: segmentIndex, index: index}, end + 1, nil
+}
+
+// IsRoot reports whether the pattern only matches the document root.
+func (p *PathPattern) IsRoot() bool {
+	return len(p.segments) == 0
+}
+
+// String returns the expression the pattern was parsed from.
+func (p *PathPattern) String() string {
+	return p.expr
+}
+
+// ShouldIgnore never matches: a path pattern only matches by location.
+func (p *PathPattern) ShouldIgnore(key, value string) bool {
+	return false
+}
+
+// ShouldIgnorePath reports whether path matches the pattern.
+func (p *PathPattern) ShouldIgnorePath(path []PathElement, value string) bool {
+	return matchSegments(p.segments, path)
+}
+
+// matchSegments reports whether segments match the whole path.
+func matchSegments(segments []pathSegment, path []PathElement) bool {
+	if len(segments) == 0 {
+		return len(path) == 0
+	}
+
+	segment := segments[0]
+	if segment.kind == segmentDescent {
+		// Match the rest at this depth or any depth below
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(segments[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+	elem := path[0]
+	switch segment.kind {
+	case segmentKey:
+		if elem.IsIndex || elem.Key != segment.key {
+			return false
+		}
+	case segmentIndex:
+		if !elem.IsIndex || elem.Index != segment.index {
+			return false
+		}
+	}
+	return matchSegments(segments[1:], path[1:])
+}
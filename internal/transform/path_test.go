@@ -0,0 +1,71 @@
+package transform
+
+import "testing"
+
+func TestPathPattern_Matches(t *testing.T) {
+	key := func(k string) PathElement { return PathElement{Key: k} }
+	index := func(i int) PathElement { return PathElement{Index: i, IsIndex: true} }
+
+	tests := []struct {
+		expr  string
+		path  []PathElement
+		match bool
+	}{
+		{"$.meta.id", []PathElement{key("meta"), key("id")}, true},
+		{"$.meta.id", []PathElement{key("id")}, false},
+		{"$.meta.id", []PathElement{key("meta"), key("id"), key("x")}, false},
+		{"$.users[*].id", []PathElement{key("users"), index(3), key("id")}, true},
+		{"$.users[*].id", []PathElement{key("users"), key("3"), key("id")}, true},
+		{"$.users[1]", []PathElement{key("users"), index(1)}, true},
+		{"$.users[1]", []PathElement{key("users"), index(2)}, false},
+		{"$.users[1]", []PathElement{key("users"), key("1")}, false},
+		{"$.*.id", []PathElement{key("a"), key("id")}, true},
+		{"$..id", []PathElement{key("id")}, true},
+		{"$..id", []PathElement{key("a"), index(0), key("id")}, true},
+		{"$..id", []PathElement{key("a"), key("idx")}, false},
+		{"$.a..[0]", []PathElement{key("a"), key("b"), index(0)}, true},
+		{"$['odd.key']['x y']", []PathElement{key("odd.key"), key("x y")}, true},
+		{`$["quoted"]`, []PathElement{key("quoted")}, true},
+		{"$", nil, true},
+		{"$", []PathElement{key("a")}, false},
+	}
+
+	for _, tt := range tests {
+		pattern, err := ParsePath(tt.expr)
+		if err != nil {
+			t.Fatalf("ParsePath(%q) failed: %v", tt.expr, err)
+		}
+		if got := pattern.ShouldIgnorePath(tt.path, ""); got != tt.match {
+			t.Errorf("%s matching %v = %v, want %v", tt.expr, tt.path, got, tt.match)
+		}
+	}
+}
+
+func TestParsePath_Errors(t *testing.T) {
+	for _, expr := range []string{"", "meta", "$.", "$..", "$[", "$[-1]", "$[x]", "$['open]", "$.a b[", "user", "users[*]", "$.users["} {
+		if _, err := ParsePath(expr); err == nil {
+			t.Errorf("ParsePath(%q): expected an error", expr)
+		}
+	}
+}
+
+func TestTransformJSON_PathPatternsKeepKeyPatterns(t *testing.T) {
+	pattern, err := ParsePath("$.a.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{Ignore: []IgnorePattern{
+		pattern,
+		&mockIgnorePattern{fn: func(key, value string) bool { return key == "secret" }},
+	}}
+
+	result, err := TransformJSON(`{"a": {"id": 1, "secret": 2}, "b": {"id": 3, "secret": 4}}`, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\n  \"a\": {},\n  \"b\": {\n    \"id\": 3\n  }\n}"
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
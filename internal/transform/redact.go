@@ -0,0 +1,171 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// Redaction replaces the values at matching locations of a JSON document.
+type Redaction struct {
+	// Path selects the values to replace. If nil, Key is used instead.
+	Path *PathPattern
+	// Key selects the values of all object keys with this name.
+	Key string
+	// Replace returns the replacement for a value, which is decoded like the
+	// rest of the document: objects are map[string]any, arrays []any, and
+	// numbers float64, or json.Number in faithful mode.
+	Replace func(value any) any
+}
+
+// matches reports whether the redaction selects the value at path.
+func (r *Redaction) matches(path []PathElement) bool {
+	if r.Path != nil {
+		return r.Path.ShouldIgnorePath(path, "")
+	}
+	last := len(path) - 1
+	return last >= 0 && !path[last].IsIndex && path[last].Key == r.Key
+}
+
+// describe names the redaction in errors.
+func (r *Redaction) describe() string {
+	if r.Path != nil {
+		return r.Path.String()
+	}
+	return fmt.Sprintf("key %q", r.Key)
+}
+
+// walkAndRedact replaces the values selected by config.Redactions, without
+// descending into replaced values.
+func walkAndRedact(data any, config *Config, path []PathElement) (any, error) {
+	for i := range config.Redactions {
+		r := &config.Redactions[i]
+		if len(path) > 0 && r.matches(path) {
+			return redact(r, data, config)
+		}
+	}
+
+	switch v := data.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, value := range v {
+			redacted, err := walkAndRedact(value, config, appendPath(path, PathElement{Key: key}))
+			if err != nil {
+				return nil, err
+			}
+			result[key] = redacted
+		}
+		return result, nil
+	case *orderedObject:
+		result := &orderedObject{values: make(map[string]any, len(v.keys))}
+		for _, key := range v.keys {
+			redacted, err := walkAndRedact(v.values[key], config, appendPath(path, PathElement{Key: key}))
+			if err != nil {
+				return nil, err
+			}
+			result.set(key, redacted)
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			redacted, err := walkAndRedact(item, config, appendPath(path, PathElement{Index: i, IsIndex: true}))
+			if err != nil {
+				return nil, err
+			}
+			result[i] = redacted
+		}
+		return result, nil
+	default:
+		return data, nil
+	}
+}
+
+// redact returns the replacement for value, re-decoded so that it is written
+// like any other part of the document.
+func redact(r *Redaction, value any, config *Config) (any, error) {
+	objects := make(map[uintptr]*orderedObject)
+	replaced := restoreOrder(r.Replace(plainValue(value, objects)), objects)
+
+	replacement, err := json.Marshal(replaced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact %s: %w", r.describe(), err)
+	}
+
+	decoded, err := decode(replacement, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redact %s: %w", r.describe(), err)
+	}
+	return decoded, nil
+}
+
+// plainValue converts objects that keep their key order to maps, so that
+// redaction callbacks only see standard decoded JSON values. Each map is
+// recorded in objects, if not nil, so that restoreOrder can find its original
+// key order.
+func plainValue(value any, objects map[uintptr]*orderedObject) any {
+	switch v := value.(type) {
+	case *orderedObject:
+		result := make(map[string]any, len(v.keys))
+		for key, item := range v.values {
+			result[key] = plainValue(item, objects)
+		}
+		if objects != nil {
+			objects[reflect.ValueOf(result).Pointer()] = v
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = plainValue(item, objects)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+// restoreOrder converts the maps made by plainValue that a redaction callback
+// returned back to ordered objects. Keys keep their original position and keys
+// added by the callback follow in sorted order. Maps the callback created are
+// left alone.
+func restoreOrder(value any, objects map[uintptr]*orderedObject) any {
+	switch v := value.(type) {
+	case map[string]any:
+		original, ok := objects[reflect.ValueOf(v).Pointer()]
+		if !ok {
+			result := make(map[string]any, len(v))
+			for key, item := range v {
+				result[key] = restoreOrder(item, objects)
+			}
+			return result
+		}
+
+		result := &orderedObject{values: make(map[string]any, len(v))}
+		for _, key := range original.keys {
+			if item, ok := v[key]; ok {
+				result.set(key, restoreOrder(item, objects))
+			}
+		}
+		added := make([]string, 0, len(v)-len(result.keys))
+		for key := range v {
+			if _, ok := result.values[key]; !ok {
+				added = append(added, key)
+			}
+		}
+		slices.Sort(added)
+		for _, key := range added {
+			result.set(key, restoreOrder(v[key], objects))
+		}
+		return result
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			result[i] = restoreOrder(item, objects)
+		}
+		return result
+	default:
+		return value
+	}
+}
@@ -0,0 +1,139 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTransformJSON_Redactions(t *testing.T) {
+	path, err := ParsePath("$.list[1]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &Config{
+		PreserveKeyOrder: true,
+		Redactions: []Redaction{
+			{Path: path, Replace: func(any) any { return "[second]" }},
+			{Key: "nested", Replace: func(value any) any { return len(value.(map[string]any)) }},
+			{Key: "id", Replace: func(any) any { return "[id]" }},
+		},
+	}
+
+	result, err := TransformJSON(`{"id": 1, "list": [{"id": 2}, {"id": 3}], "nested": {"id": 4, "b": 5}}`, config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Replaced values are not redacted again
+	expected := `{
+  "id": "[id]",
+  "list": [
+    {
+      "id": "[id]"
+    },
+    "[second]"
+  ],
+  "nested": 2
+}`
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+func TestTransformJSON_RedactionsKeepKeyOrder(t *testing.T) {
+	tests := map[string]struct {
+		replace  func(any) any
+		expected string
+	}{
+		"unchanged": {
+			replace:  func(value any) any { return value },
+			expected: `{"order":{"total":3,"b":{"y":1,"x":2},"a":[{"d":1,"c":2}]}}`,
+		},
+		"edited": {
+			replace: func(value any) any {
+				order := value.(map[string]any)
+				delete(order, "total")
+				order["z"] = 1
+				order["e"] = 2
+				return order
+			},
+			expected: `{"order":{"b":{"y":1,"x":2},"a":[{"d":1,"c":2}],"e":2,"z":1}}`,
+		},
+		"wrapped": {
+			replace:  func(value any) any { return map[string]any{"wrapped": value} },
+			expected: `{"order":{"wrapped":{"total":3,"b":{"y":1,"x":2},"a":[{"d":1,"c":2}]}}}`,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &Config{
+				PreserveKeyOrder: true,
+				Redactions:       []Redaction{{Key: "order", Replace: tt.replace}},
+			}
+			result, err := TransformJSON(`{"order": {"total": 3, "b": {"y": 1, "x": 2}, "a": [{"d": 1, "c": 2}]}}`, config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, []byte(result)); err != nil {
+				t.Fatal(err)
+			}
+			if compacted.String() != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, compacted.String())
+			}
+		})
+	}
+}
+
+func TestTransformJSON_RedactionsSeePlainValues(t *testing.T) {
+	var got any
+	config := &Config{
+		Faithful:         true,
+		PreserveKeyOrder: true,
+		Redactions: []Redaction{{Key: "order", Replace: func(value any) any {
+			got = value
+			return value
+		}}},
+	}
+
+	if _, err := TransformJSON(`{"order": {"total": 12345678901234567890, "b": 1}}`, config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	order, ok := got.(map[string]any)
+	if !ok || order["total"] != json.Number("12345678901234567890") {
+		t.Errorf("expected a plain map with verbatim numbers, got %#v", got)
+	}
+}
+
+func TestTransformJSON_RedactionErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		redaction Redaction
+		expected  string
+	}{
+		{
+			name:      "unencodable_path_replacement",
+			redaction: Redaction{Path: mustParsePath(t, "$.a"), Replace: func(any) any { return make(chan int) }},
+			expected:  "failed to redact $.a",
+		},
+		{
+			name:      "unencodable_key_replacement",
+			redaction: Redaction{Key: "a", Replace: func(any) any { return func() {} }},
+			expected:  `failed to redact key "a"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Redactions: []Redaction{tt.redaction}}
+			_, err := TransformJSON(`{"a": 1}`, config)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
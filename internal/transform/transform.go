@@ -3,6 +3,7 @@ package transform
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Scrubber transforms content before snapshotting.
@@ -18,7 +19,20 @@ type IgnorePattern interface {
 // Config holds the transformation configuration.
 type Config struct {
 	Scrubbers []Scrubber
-	Ignore    []IgnorePattern
+	// Include, if set, keeps only the values at matching paths, along with
+	// the objects and arrays containing them. It is applied before Ignore.
+	Include []*PathPattern
+	Ignore  []IgnorePattern
+	// Faithful keeps numbers exactly as written and does not escape <, > and
+	// & in strings, instead of normalizing them like encoding/json.
+	Faithful bool
+	// PreserveKeyOrder keeps object keys in their source order instead of
+	// sorting them.
+	PreserveKeyOrder bool
+	// Arrays drop and sort array elements after ignore patterns are applied.
+	Arrays []ArrayRule
+	// Redactions replace matching values after arrays are processed.
+	Redactions []Redaction
 }
 
 // ApplyScrubbers applies all scrubbers to the content in order.
@@ -32,23 +46,54 @@ func ApplyScrubbers(content string, scrubbers []Scrubber) string {
 
 // TransformJSON applies scrubbers and ignore patterns to JSON data.
 func TransformJSON(jsonStr string, config *Config) (string, error) {
-	var data any
-	if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
+	return TransformJSONBytes([]byte(jsonStr), config)
+}
+
+// TransformJSONBytes is like TransformJSON for JSON data held in a byte slice.
+func TransformJSONBytes(jsonData []byte, config *Config) (string, error) {
+	data, err := decode(jsonData, config)
+	if err != nil {
 		return "", fmt.Errorf("failed to unmarshal JSON: %w", err)
 	}
 
-	// Apply ignore patterns first (removes fields)
+	// Keep only included values, pruning everything else
+	if len(config.Include) > 0 {
+		data = walkInclude(data, config.Include, nil)
+	}
+
+	// Apply ignore patterns (removes fields)
 	if len(config.Ignore) > 0 {
 		data = walkAndFilter(data, config.Ignore)
 	}
 
-	// Marshal back to JSON
-	prettyJSON, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	// Drop and sort array elements before redactions hide the values
+	if len(config.Arrays) > 0 {
+		data = walkArrays(data, config.Arrays, nil)
 	}
 
-	result := string(prettyJSON)
+	// Then replace redacted values, keeping the structure visible
+	if len(config.Redactions) > 0 {
+		data, err = walkAndRedact(data, config, nil)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Marshal back to JSON
+	var result string
+	if config.Faithful || config.PreserveKeyOrder {
+		var sb strings.Builder
+		if err := writeIndented(&sb, data, "", !config.Faithful); err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		result = sb.String()
+	} else {
+		prettyJSON, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		result = string(prettyJSON)
+	}
 
 	// Apply scrubbers to the final string
 	result = ApplyScrubbers(result, config.Scrubbers)
@@ -56,51 +101,116 @@ func TransformJSON(jsonStr string, config *Config) (string, error) {
 	return result, nil
 }
 
+// decode decodes JSON data in the mode selected by config.
+func decode(jsonData []byte, config *Config) (any, error) {
+	if config.Faithful || config.PreserveKeyOrder {
+		return decodeFaithful(jsonData, config)
+	}
+
+	var data any
+	err := json.Unmarshal(jsonData, &data)
+	return data, err
+}
+
 // walkAndFilter recursively walks the data structure and filters out ignored fields.
 func walkAndFilter(data any, ignorePatterns []IgnorePattern) any {
+	return walkPath(data, ignorePatterns, nil)
+}
+
+// walkPath is walkAndFilter for the value at path.
+func walkPath(data any, ignorePatterns []IgnorePattern, path []PathElement) any {
 	switch v := data.(type) {
 	case map[string]any:
-		return filterMap(v, ignorePatterns)
+		return filterMap(v, ignorePatterns, path)
+	case *orderedObject:
+		return filterOrdered(v, ignorePatterns, path)
 	case []any:
-		return filterSlice(v, ignorePatterns)
+		return filterSlice(v, ignorePatterns, path)
 	default:
 		return data
 	}
 }
 
 // filterMap filters a map, removing entries that match ignore patterns.
-func filterMap(m map[string]any, ignorePatterns []IgnorePattern) map[string]any {
+func filterMap(m map[string]any, ignorePatterns []IgnorePattern, path []PathElement) map[string]any {
 	result := make(map[string]any)
 	for key, value := range m {
-		// Convert value to string for comparison
-		valueStr := valueToString(value)
-
-		// Check if this key-value pair should be ignored
-		shouldIgnore := false
-		for _, pattern := range ignorePatterns {
-			if pattern.ShouldIgnore(key, valueStr) {
-				shouldIgnore = true
-				break
-			}
+		keyPath := appendPath(path, PathElement{Key: key})
+		if !shouldIgnore(key, value, ignorePatterns, keyPath) {
+			// Recursively filter nested structures
+			result[key] = walkPath(value, ignorePatterns, keyPath)
 		}
+	}
+	return result
+}
 
-		if !shouldIgnore {
-			// Recursively filter nested structures
-			result[key] = walkAndFilter(value, ignorePatterns)
+// filterOrdered is filterMap for objects that keep their key order.
+func filterOrdered(o *orderedObject, ignorePatterns []IgnorePattern, path []PathElement) *orderedObject {
+	result := &orderedObject{values: make(map[string]any)}
+	for _, key := range o.keys {
+		value := o.values[key]
+		keyPath := appendPath(path, PathElement{Key: key})
+		if !shouldIgnore(key, value, ignorePatterns, keyPath) {
+			result.set(key, walkPath(value, ignorePatterns, keyPath))
 		}
 	}
 	return result
 }
 
-// filterSlice filters a slice, recursively processing each element.
-func filterSlice(s []any, ignorePatterns []IgnorePattern) []any {
-	result := make([]any, len(s))
+// shouldIgnore reports whether any ignore pattern matches a key-value pair
+// at path.
+func shouldIgnore(key string, value any, ignorePatterns []IgnorePattern, path []PathElement) bool {
+	// Convert value to string for comparison
+	valueStr := valueToString(value)
+
+	for _, pattern := range ignorePatterns {
+		if pathPattern, ok := pattern.(PathIgnorePattern); ok && pathPattern.ShouldIgnorePath(path, valueStr) {
+			return true
+		}
+		if pattern.ShouldIgnore(key, valueStr) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterSlice filters a slice, recursively processing each element. Only path
+// patterns remove elements.
+func filterSlice(s []any, ignorePatterns []IgnorePattern, path []PathElement) []any {
+	result := make([]any, 0, len(s))
 	for i, item := range s {
-		result[i] = walkAndFilter(item, ignorePatterns)
+		itemPath := appendPath(path, PathElement{Index: i, IsIndex: true})
+		if shouldIgnoreElement(item, ignorePatterns, itemPath) {
+			continue
+		}
+		result = append(result, walkPath(item, ignorePatterns, itemPath))
 	}
 	return result
 }
 
+// shouldIgnoreElement reports whether a path pattern matches an array element.
+func shouldIgnoreElement(item any, ignorePatterns []IgnorePattern, path []PathElement) bool {
+	var valueStr string
+	for _, pattern := range ignorePatterns {
+		pathPattern, ok := pattern.(PathIgnorePattern)
+		if !ok {
+			continue
+		}
+		if valueStr == "" {
+			valueStr = valueToString(item)
+		}
+		if pathPattern.ShouldIgnorePath(path, valueStr) {
+			return true
+		}
+	}
+	return false
+}
+
+// appendPath returns path extended by elem without modifying path.
+func appendPath(path []PathElement, elem PathElement) []PathElement {
+	return append(path[:len(path):len(path)], elem)
+}
+
 // valueToString converts various value types to string for comparison.
 func valueToString(value any) string {
 	switch v := value.(type) {
@@ -115,6 +225,8 @@ func valueToString(value any) string {
 		return "false"
 	case float64:
 		return fmt.Sprintf("%v", v)
+	case json.Number:
+		return v.String()
 	case int, int64:
 		return fmt.Sprintf("%d", v)
 	default:
@@ -124,6 +124,22 @@ func TestTransformJSON_EmptyConfig(t *testing.T) {
 	}
 }
 
+func TestTransformJSONBytes(t *testing.T) {
+	config := &Config{Ignore: []IgnorePattern{&mockIgnorePattern{
+		fn: func(key, value string) bool { return key == "age" },
+	}}}
+
+	result, err := TransformJSONBytes([]byte(`{"name":"John","age":30}`), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "{\n  \"name\": \"John\"\n}"
+	if result != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
 func TestTransformJSON_WithScrubbers(t *testing.T) {
 	scrubber := &mockScrubber{
 		fn: func(s string) string {
@@ -346,7 +362,7 @@ func TestFilterMap_RemovesMatchingKeys(t *testing.T) {
 		"also_keep": "value3",
 	}
 
-	result := filterMap(input, []IgnorePattern{ignorePattern})
+	result := filterMap(input, []IgnorePattern{ignorePattern}, nil)
 
 	if _, exists := result["remove_me"]; exists {
 		t.Error("expected 'remove_me' to be filtered out")
@@ -374,7 +390,7 @@ func TestFilterMap_NestedStructures(t *testing.T) {
 		},
 	}
 
-	result := filterMap(input, []IgnorePattern{ignorePattern})
+	result := filterMap(input, []IgnorePattern{ignorePattern}, nil)
 
 	nested, ok := result["nested"].(map[string]any)
 	if !ok {
@@ -401,7 +417,7 @@ func TestFilterSlice_ProcessesAllElements(t *testing.T) {
 		map[string]any{"id": "2", "name": "Bob"},
 	}
 
-	result := filterSlice(input, []IgnorePattern{ignorePattern})
+	result := filterSlice(input, []IgnorePattern{ignorePattern}, nil)
 
 	if len(result) != 2 {
 		t.Fatalf("expected 2 elements, got %d", len(result))
@@ -0,0 +1,171 @@
+package shutter
+
+import "maps"
+
+// setting is an Option that configures how a snapshot is stored rather than
+// transforming its content.
+type setting interface {
+	Option
+	apply(*snapOptions)
+}
+
+// titleTemplate sets the template used to derive automatic titles.
+type titleTemplate struct {
+	template string
+}
+
+func (t *titleTemplate) isOption() {}
+
+func (t *titleTemplate) apply(o *snapOptions) {
+	o.titleTemplate = t.template
+}
+
+// WithTitleTemplate sets the template used to derive the snapshot title when
+// an empty title is passed. The following placeholders are supported:
+//
+//	{test}  the full test name, e.g. "TestUser/admin"
+//	{name}  the last segment of the test name, e.g. "admin"
+//	{n}     a per-test counter starting at 1, incremented on every call
+//
+// Without this option the title is the full test name. Repeated titles within
+// one test are told apart with a counter suffix (e.g. "TestUser#01").
+//
+// Example:
+//
+//	t.Run("admin", func(t *testing.T) {
+//	    shutter.Snap(t, "", first, shutter.WithTitleTemplate("{test}/{n}"))  // "TestUser/admin/1"
+//	    shutter.Snap(t, "", second, shutter.WithTitleTemplate("{test}/{n}")) // "TestUser/admin/2"
+//	})
+func WithTitleTemplate(template string) Option {
+	return &titleTemplate{
+		template: template,
+	}
+}
+
+// callerSkip sets the number of extra stack frames to skip.
+type callerSkip struct {
+	skip int
+}
+
+func (c *callerSkip) isOption() {}
+
+func (c *callerSkip) apply(o *snapOptions) {
+	o.callerSkip = c.skip
+}
+
+// CallerSkip attributes the snapshot to the caller skip frames further up the
+// stack than the code calling shutter, much like the skip argument of
+// runtime.Caller. It is an alternative to Helper for wrappers that cannot
+// call it, such as generic assertion libraries.
+//
+// Example:
+//
+//	// snapResponse is called from tests; record the test's line, not this one
+//	func snapResponse(t *testing.T, resp *http.Response) {
+//	    shutter.Snap(t, "", dump(resp), shutter.CallerSkip(1))
+//	}
+func CallerSkip(skip int) Option {
+	return &callerSkip{
+		skip: skip,
+	}
+}
+
+// description sets the description stored with a snapshot.
+type description struct {
+	text string
+}
+
+func (d *description) isOption() {}
+
+func (d *description) apply(o *snapOptions) {
+	o.info.Description = d.text
+}
+
+// WithDescription stores a description in the snapshot header that is shown
+// when reviewing the snapshot, so reviewers know what they are approving. It
+// does not affect comparisons.
+//
+// Example:
+//
+//	shutter.Snap(t, "", resp, shutter.WithDescription("GET /users/123 as an admin"))
+func WithDescription(text string) Option {
+	return &description{
+		text: text,
+	}
+}
+
+// metadata adds key/value pairs to the metadata stored with a snapshot.
+type metadata struct {
+	values map[string]string
+}
+
+func (m *metadata) isOption() {}
+
+func (m *metadata) apply(o *snapOptions) {
+	if o.info.Metadata == nil {
+		o.info.Metadata = map[string]string{}
+	}
+	maps.Copy(o.info.Metadata, m.values)
+}
+
+// WithMetadata stores arbitrary key/value pairs in the snapshot header, such
+// as the input that produced the snapshotted output. They are shown when
+// reviewing the snapshot and do not affect comparisons. Passing the option
+// several times merges the pairs, with later values winning.
+//
+// Example:
+//
+//	shutter.Snap(t, "", render(input), shutter.WithMetadata(map[string]string{
+//	    "input": input,
+//	}))
+func WithMetadata(values map[string]string) Option {
+	return &metadata{
+		values: maps.Clone(values),
+	}
+}
+
+// jsonMode sets how the SnapJSON functions re-encode JSON.
+type jsonMode struct {
+	faithful         bool
+	preserveKeyOrder bool
+}
+
+func (j *jsonMode) isOption() {}
+
+func (j *jsonMode) apply(o *snapOptions) {
+	o.faithfulJSON = o.faithfulJSON || j.faithful
+	o.preserveKeyOrder = o.preserveKeyOrder || j.preserveKeyOrder
+}
+
+// FaithfulJSON makes the SnapJSON functions keep JSON as clients receive it:
+// numbers are written exactly as in the input, so large integer IDs keep
+// their precision and never switch to exponent form, and <, > and & in
+// strings are not escaped. Keys are still sorted unless PreserveKeyOrder is
+// also given. Ignore patterns and scrubbers work as usual.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "order", `{"id": 9007199254740993, "note": "<b>"}`,
+//	    shutter.FaithfulJSON(),
+//	)
+func FaithfulJSON() Option {
+	return &jsonMode{
+		faithful: true,
+	}
+}
+
+// PreserveKeyOrder makes the SnapJSON functions keep object keys in their
+// source order instead of sorting them. With SnapJSONValue, struct fields
+// keep their declaration order.
+//
+// Example:
+//
+//	shutter.SnapJSONBytes(t, "response", body,
+//	    shutter.FaithfulJSON(),
+//	    shutter.PreserveKeyOrder(),
+//	)
+func PreserveKeyOrder() Option {
+	return &jsonMode{
+		preserveKeyOrder: true,
+	}
+}
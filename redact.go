@@ -0,0 +1,85 @@
+package shutter
+
+import "github.com/ptdewey/shutter/internal/transform"
+
+// redaction replaces JSON values before snapshotting.
+type redaction struct {
+	redaction transform.Redaction
+}
+
+func (r *redaction) isOption() {}
+
+func (r *redaction) apply(o *snapOptions) {
+	o.redactions = append(o.redactions, r.redaction)
+}
+
+// Redact creates a redaction that replaces the values at a JSONPath-like path
+// (see IgnorePath for the syntax) with replacement. Unlike ignore patterns,
+// redactions keep the redacted fields in the snapshot, so its structure stays
+// visible. Redactions are applied after ignore patterns and before scrubbers.
+// Redact panics if the path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.Redact("$.users[*].created_at", "[timestamp]"),
+//	)
+func Redact(path string, replacement any) Option {
+	return RedactWith(path, func(any) any {
+		return replacement
+	})
+}
+
+// RedactKey creates a redaction that replaces the values of every key with
+// the given name, at any depth, with replacement.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.RedactKey("id", "[id]"),
+//	)
+func RedactKey(key string, replacement any) Option {
+	return &redaction{
+		redaction: transform.Redaction{
+			Key: key,
+			Replace: func(any) any {
+				return replacement
+			},
+		},
+	}
+}
+
+// RedactWith creates a redaction that replaces the values at a JSONPath-like
+// path with the result of fn. fn receives the value as decoded by
+// encoding/json: objects are map[string]any, arrays []any, and numbers
+// float64, or json.Number with FaithfulJSON. Its result is encoded as JSON.
+// RedactWith panics if the path is invalid.
+//
+// This option only works with SnapJSON.
+//
+// Example:
+//
+//	shutter.SnapJSON(t, "response", jsonStr,
+//	    shutter.RedactWith("$.items", func(value any) any {
+//	        return fmt.Sprintf("[%d items]", len(value.([]any)))
+//	    }),
+//	)
+func RedactWith(path string, fn func(value any) any) Option {
+	pattern, err := transform.ParsePath(path)
+	if err != nil {
+		panic("shutter: redaction: " + err.Error())
+	}
+	if pattern.IsRoot() {
+		panic("shutter: redaction: the document root cannot be redacted")
+	}
+	return &redaction{
+		redaction: transform.Redaction{
+			Path:    pattern,
+			Replace: fn,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package shutter_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ptdewey/shutter"
+)
+
+func TestRedactions(t *testing.T) {
+	jsonStr := `{
+		"id": 42,
+		"users": [
+			{"id": 1, "name": "Ann", "created_at": "2024-01-01T10:00:00Z"},
+			{"id": 2, "name": "Bob", "created_at": "2024-02-01T11:30:00Z"}
+		],
+		"items": ["a", "b", "c"],
+		"session": {"token": "abc", "expires": 1700000000},
+		"debug": {"trace": "x"}
+	}`
+
+	shutter.SnapJSON(t, "Redactions", jsonStr,
+		shutter.IgnoreKey("debug"),
+		shutter.Redact("$.users[*].created_at", "[timestamp]"),
+		shutter.RedactKey("id", "[id]"),
+		shutter.RedactWith("$.items", func(value any) any {
+			return fmt.Sprintf("[%d items]", len(value.([]any)))
+		}),
+		shutter.Redact("$.session", map[string]any{"token": "[token]"}),
+	)
+}
+
+func TestRedactWith_FaithfulValues(t *testing.T) {
+	var got any
+	shutter.SnapJSON(t, "Faithful Redactions", `{"order": {"total": 12345678901234567890, "b": 1, "a": 2}}`,
+		shutter.FaithfulJSON(),
+		shutter.PreserveKeyOrder(),
+		shutter.RedactWith("$.order", func(value any) any {
+			got = value
+			return value
+		}),
+	)
+
+	order, ok := got.(map[string]any)
+	if !ok || order["total"] != json.Number("12345678901234567890") {
+		t.Errorf("expected a plain map with verbatim numbers, got %#v", got)
+	}
+}
+
+func TestRedact_UnencodableReplacement(t *testing.T) {
+	rec := &errorRecorder{T: t}
+	shutter.SnapJSON(rec, "unencodable redaction", `{"a": 1}`, shutter.Redact("$.a", make(chan int)))
+
+	if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "failed to redact $.a") {
+		t.Errorf("unexpected errors %q", rec.errors)
+	}
+}
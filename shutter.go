@@ -1,9 +1,16 @@
 package shutter
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/kortschak/utter"
+	"github.com/ptdewey/shutter/internal/diff"
+	"github.com/ptdewey/shutter/internal/files"
 	"github.com/ptdewey/shutter/internal/review"
 	"github.com/ptdewey/shutter/internal/snapshots"
 	"github.com/ptdewey/shutter/internal/transform"
@@ -12,7 +19,7 @@ import (
 // snapshotFormatVersion indicates the snapshot format version used by this library.
 // This is automatically included in snapshot metadata for compatibility checking
 // when the snapshot format changes in future versions.
-const snapshotFormatVersion = "0.1.0"
+const snapshotFormatVersion = files.FormatVersion
 
 // utterConfig is a configured instance of utter for consistent formatting.
 // This avoids modifying global state and ensures snapshot formatting is isolated.
@@ -59,6 +66,8 @@ type IgnorePattern interface {
 // Snap takes a single value, formats it, and creates a snapshot with the given title.
 // Complex types are formatted using a pretty-printer for readability.
 //
+// An empty title is derived from the test name; see WithTitleTemplate.
+//
 // Options can be provided to scrub sensitive or dynamic data before snapshotting.
 // Only Scrubber options are supported; IgnorePattern options will cause an error.
 //
@@ -72,21 +81,23 @@ type IgnorePattern interface {
 func Snap(t snapshots.T, title string, value any, opts ...Option) {
 	t.Helper()
 
-	scrubbers, ignores := separateOptions(opts)
+	o := collectOptions(opts)
+	title = resolveTitle(t, title, o)
 
-	if len(ignores) > 0 {
-		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with Snap; use SnapJSON instead", title))
+	if kind := o.jsonOnly(); kind != "" {
+		t.Error(fmt.Sprintf("snapshot %q: %s options are not supported with Snap; use SnapJSON instead", title, kind))
 		return
 	}
 
 	content := formatValue(value)
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	scrubbedContent := applyScrubbers(content, o.scrubbers)
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	snapshots.SnapSkip(t, o.callerSkip, o.info, title, snapshotFormatVersion, scrubbedContent)
 }
 
 // SnapMany takes multiple values, formats them, and creates a snapshot with the given title.
 // This is useful when you want to snapshot multiple related values together.
+// An empty title is derived from the test name.
 //
 // Options can be provided to scrub sensitive or dynamic data before snapshotting.
 // Only Scrubber options are supported; IgnorePattern options will cause an error.
@@ -101,21 +112,66 @@ func Snap(t snapshots.T, title string, value any, opts ...Option) {
 func SnapMany(t snapshots.T, title string, values []any, opts ...Option) {
 	t.Helper()
 
-	scrubbers, ignores := separateOptions(opts)
+	o := collectOptions(opts)
+	title = resolveTitle(t, title, o)
 
-	if len(ignores) > 0 {
-		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with SnapMany; use SnapJSON instead", title))
+	if kind := o.jsonOnly(); kind != "" {
+		t.Error(fmt.Sprintf("snapshot %q: %s options are not supported with SnapMany; use SnapJSON instead", title, kind))
 		return
 	}
 
 	content := formatValues(values...)
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	scrubbedContent := applyScrubbers(content, o.scrubbers)
+
+	snapshots.SnapSkip(t, o.callerSkip, o.info, title, snapshotFormatVersion, scrubbedContent)
+}
+
+// Section is a named value in a snapshot taken with SnapSections.
+type Section struct {
+	Name  string
+	Value any
+}
+
+// SnapSections formats each section's value like Snap and creates a snapshot
+// with a "=== name ===" header line before every section, so that diffs show
+// which value changed. When reviewing, unchanged sections are collapsed and
+// changes are grouped by section. An empty title is derived from the test name.
+//
+// Section names must be unique, non-empty, single-line strings. Only Scrubber
+// options are supported; IgnorePattern options will cause an error.
+//
+// Example:
+//
+//	shutter.SnapSections(t, "create user", []shutter.Section{
+//	    {Name: "request", Value: req},
+//	    {Name: "response", Value: resp},
+//	}, shutter.ScrubUUID())
+func SnapSections(t snapshots.T, title string, sections []Section, opts ...Option) {
+	t.Helper()
+
+	o := collectOptions(opts)
+	title = resolveTitle(t, title, o)
+
+	if kind := o.jsonOnly(); kind != "" {
+		t.Error(fmt.Sprintf("snapshot %q: %s options are not supported with SnapSections; use SnapJSON instead", title, kind))
+		return
+	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	content, err := formatSections(sections)
+	if err != nil {
+		t.Error(fmt.Sprintf("snapshot %q: %v", title, err))
+		return
+	}
+	scrubbedContent := applyScrubbers(content, o.scrubbers)
+
+	info := o.info
+	info.Sections = true
+	snapshots.SnapSkip(t, o.callerSkip, info, title, snapshotFormatVersion, scrubbedContent)
 }
 
 // SnapString takes a string value and creates a snapshot with the given title.
 // This is useful for snapshotting generated text, logs, or other string content.
+// An empty title is derived from the test name.
 //
 // Options can be provided to scrub sensitive or dynamic data before snapshotting.
 // Only Scrubber options are supported; IgnorePattern options will cause an error.
@@ -129,21 +185,23 @@ func SnapMany(t snapshots.T, title string, values []any, opts ...Option) {
 func SnapString(t snapshots.T, title string, content string, opts ...Option) {
 	t.Helper()
 
-	scrubbers, ignores := separateOptions(opts)
+	o := collectOptions(opts)
+	title = resolveTitle(t, title, o)
 
-	if len(ignores) > 0 {
-		t.Error(fmt.Sprintf("snapshot %q: IgnorePattern options are not supported with SnapString; use SnapJSON instead", title))
+	if kind := o.jsonOnly(); kind != "" {
+		t.Error(fmt.Sprintf("snapshot %q: %s options are not supported with SnapString; use SnapJSON instead", title, kind))
 		return
 	}
 
-	scrubbedContent := applyScrubbers(content, scrubbers)
+	scrubbedContent := applyScrubbers(content, o.scrubbers)
 
-	snapshots.Snap(t, title, snapshotFormatVersion, scrubbedContent)
+	snapshots.SnapSkip(t, o.callerSkip, o.info, title, snapshotFormatVersion, scrubbedContent)
 }
 
 // SnapJSON takes a JSON string, validates it, and pretty-prints it with
 // consistent formatting before snapshotting. This preserves the raw JSON
-// format while ensuring valid JSON structure.
+// format while ensuring valid JSON structure. An empty title is derived
+// from the test name.
 //
 // Options can be provided to apply both Scrubbers and IgnorePatterns.
 // IgnorePatterns remove fields from the JSON structure before scrubbing.
@@ -159,22 +217,369 @@ func SnapString(t snapshots.T, title string, content string, opts ...Option) {
 //	)
 func SnapJSON(t snapshots.T, title string, jsonStr string, opts ...Option) {
 	t.Helper()
+	snapJSON(t, title, []byte(jsonStr), opts)
+}
+
+// SnapJSONBytes is like SnapJSON for JSON data held in a byte slice, such as
+// a recorded response body.
+//
+// Example:
+//
+//	shutter.SnapJSONBytes(t, "user response", rec.Body.Bytes(), shutter.ScrubUUID())
+func SnapJSONBytes(t snapshots.T, title string, data []byte, opts ...Option) {
+	t.Helper()
+	snapJSON(t, title, data, opts)
+}
+
+// SnapJSONReader is like SnapJSON for JSON data read from r until EOF.
+//
+// Example:
+//
+//	resp, _ := http.Get(server.URL + "/users/123")
+//	defer resp.Body.Close()
+//	shutter.SnapJSONReader(t, "user response", resp.Body, shutter.IgnoreKey("updated_at"))
+func SnapJSONReader(t snapshots.T, title string, r io.Reader, opts ...Option) {
+	t.Helper()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		o := collectOptions(opts)
+		t.Error(fmt.Sprintf("snapshot %q: failed to read JSON: %v", resolveTitle(t, title, o), err))
+		return
+	}
+
+	snapJSON(t, title, data, opts)
+}
+
+// SnapJSONValue marshals value with encoding/json, honoring json struct tags
+// and custom marshalers, and snapshots the result like SnapJSON.
+//
+// Example:
+//
+//	user := User{ID: "550e8400-...", Email: "user@example.com", Password: "secret"}
+//	shutter.SnapJSONValue(t, "user", user,
+//	    shutter.IgnoreKey("password"),
+//	    shutter.ScrubUUID(),
+//	)
+func SnapJSONValue(t snapshots.T, title string, value any, opts ...Option) {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		o := collectOptions(opts)
+		t.Error(fmt.Sprintf("snapshot %q: failed to marshal JSON: %v", resolveTitle(t, title, o), err))
+		return
+	}
+
+	snapJSON(t, title, data, opts)
+}
+
+// snapJSON transforms JSON data with the ignore patterns and scrubbers in
+// opts and snapshots the result.
+func snapJSON(t snapshots.T, title string, data []byte, opts []Option) {
+	t.Helper()
 
-	scrubbers, ignores := separateOptions(opts)
+	o := collectOptions(opts)
+	title = resolveTitle(t, title, o)
 
 	// Transform the JSON with ignore patterns and scrubbers
 	transformConfig := &transform.Config{
-		Scrubbers: toTransformScrubbers(scrubbers),
-		Ignore:    toTransformIgnorePatterns(ignores),
+		Scrubbers:        toTransformScrubbers(o.scrubbers),
+		Include:          o.includes,
+		Ignore:           toTransformIgnorePatterns(o.ignores),
+		Faithful:         o.faithfulJSON,
+		PreserveKeyOrder: o.preserveKeyOrder,
+		Arrays:           o.arrayRules,
+		Redactions:       o.redactions,
 	}
 
-	transformedJSON, err := transform.TransformJSON(jsonStr, transformConfig)
+	transformedJSON, err := transform.TransformJSONBytes(data, transformConfig)
 	if err != nil {
 		t.Error(fmt.Sprintf("snapshot %q: failed to transform JSON: %v", title, err))
 		return
 	}
 
-	snapshots.Snap(t, title, snapshotFormatVersion, transformedJSON)
+	snapshots.SnapSkip(t, o.callerSkip, o.info, title, snapshotFormatVersion, transformedJSON)
+}
+
+// SnapInline compares a value against an expected value written inline in the
+// test file, instead of a separate .snap file. Strings are compared verbatim;
+// other values are formatted like Snap. Leading and trailing blank lines and
+// common indentation are ignored, so multi-line expectations can be written as
+// indented raw strings.
+//
+// On mismatch a pending update is recorded, and accepting it with
+// 'shutter review' rewrites the expected literal in the source. Start with an
+// empty literal to have shutter fill it in. The expected value must be a
+// string literal.
+//
+// Options can be provided to scrub sensitive or dynamic data before comparing.
+// Only Scrubber options are supported; other options, such as IgnorePattern or
+// WithDescription, will cause an error.
+//
+// Example:
+//
+//	shutter.SnapInline(t, user, `
+//	    User{
+//	      Name: "Alice",
+//	    }
+//	`)
+func SnapInline(t snapshots.T, value any, expected string, opts ...Option) {
+	t.Helper()
+
+	o := collectOptions(opts)
+
+	kind := o.jsonOnly()
+	if kind == "" {
+		kind = o.fileOnly()
+	}
+	if kind != "" {
+		t.Error("inline snapshot: " + kind + " options are not supported with SnapInline")
+		return
+	}
+
+	content, ok := value.(string)
+	if !ok {
+		content = formatValue(value)
+	}
+	scrubbedContent := applyScrubbers(content, o.scrubbers)
+
+	snapshots.SnapInline(t, expected, scrubbedContent)
+}
+
+// Snapshot is a stored snapshot: its header fields and content.
+type Snapshot = files.Snapshot
+
+// DiffKind tells whether a DiffLine was removed, added or is shared.
+type DiffKind = diff.DiffKind
+
+const (
+	// DiffShared marks a line present in both snapshots.
+	DiffShared = diff.DiffShared
+	// DiffOld marks a line only present in the accepted snapshot.
+	DiffOld = diff.DiffOld
+	// DiffNew marks a line only present in the new snapshot.
+	DiffNew = diff.DiffNew
+)
+
+// DiffLine is one line of the diff between two snapshots.
+type DiffLine = diff.DiffLine
+
+// Status is the outcome of comparing a value with its accepted snapshot.
+type Status = snapshots.Status
+
+const (
+	// StatusMatch means the value equals the accepted snapshot.
+	StatusMatch = snapshots.StatusMatch
+	// StatusNew means there is no accepted snapshot yet.
+	StatusNew = snapshots.StatusNew
+	// StatusChanged means the value differs from the accepted snapshot.
+	StatusChanged = snapshots.StatusChanged
+)
+
+// Result describes how a value compares with its accepted snapshot: the
+// Status, the Accepted and New snapshots, and the Diff between them.
+type Result = snapshots.Result
+
+// Compare formats value like Snap and compares it with the accepted snapshot
+// stored under title. Unlike Snap, it neither writes files nor fails a test,
+// leaving the caller to decide what to do with the result. This suits custom
+// assertion helpers and golden-file generators run outside of tests.
+//
+// Since there is no test to derive it from, title must not be empty.
+//
+// Example:
+//
+//	result, err := shutter.Compare("user data", user, shutter.ScrubUUID())
+//	if err != nil {
+//	    return err
+//	}
+//	if result.Status != shutter.StatusMatch {
+//	    fmt.Printf("%s is %s\n", result.Name, result.Status)
+//	}
+func Compare(title string, value any, opts ...Option) (*Result, error) {
+	return compareContent("Compare", title, formatValue(value), opts)
+}
+
+// CompareString is like Compare for string content, which is used verbatim.
+func CompareString(title string, content string, opts ...Option) (*Result, error) {
+	return compareContent("CompareString", title, content, opts)
+}
+
+// compareContent applies the scrubbers in opts to content and compares it
+// with the accepted snapshot stored under title. fn names the caller in errors.
+func compareContent(fn, title, content string, opts []Option) (*Result, error) {
+	o := collectOptions(opts)
+
+	if title == "" {
+		return nil, fmt.Errorf("%s: a title is required outside of a test", fn)
+	}
+	if kind := o.jsonOnly(); kind != "" {
+		return nil, fmt.Errorf("snapshot %q: %s options are not supported with %s", title, kind, fn)
+	}
+
+	return snapshots.CompareContent(o.info, title, snapshotFormatVersion, applyScrubbers(content, o.scrubbers))
+}
+
+// UpdateMode controls how new and changed snapshots are written.
+type UpdateMode = snapshots.UpdateMode
+
+const (
+	// UpdateAuto uses UpdateNo when a CI environment is detected and
+	// UpdatePending otherwise. This is the default.
+	UpdateAuto = snapshots.UpdateAuto
+	// UpdatePending writes .snap.new files for review and fails the test.
+	UpdatePending = snapshots.UpdatePending
+	// UpdateAlways overwrites accepted snapshots in place and passes.
+	UpdateAlways = snapshots.UpdateAlways
+	// UpdateNew accepts unseen snapshots in place and handles changed
+	// snapshots like UpdatePending.
+	UpdateNew = snapshots.UpdateNew
+	// UpdateNo never writes snapshot files and fails on any difference.
+	UpdateNo = snapshots.UpdateNo
+)
+
+// SetUpdateMode sets the update mode for the current test binary, overriding
+// the SHUTTER_UPDATE environment variable. It is typically called from TestMain.
+//
+// Example:
+//
+//	func TestMain(m *testing.M) {
+//	    shutter.SetUpdateMode(shutter.UpdateNo)
+//	    os.Exit(m.Run())
+//	}
+func SetUpdateMode(mode UpdateMode) {
+	snapshots.SetUpdateMode(mode)
+}
+
+// Verbosity controls how much of a new or changed snapshot is reported.
+type Verbosity = snapshots.Verbosity
+
+const (
+	// VerbosityFull reports the full snapshot or diff in a box. This is the
+	// default.
+	VerbosityFull = snapshots.VerbosityFull
+	// VerbosityCompact reports only the changed hunks of a diff and the
+	// first lines of a new snapshot.
+	VerbosityCompact = snapshots.VerbosityCompact
+	// VerbositySummary reports a single line per snapshot, which suits
+	// large suites.
+	VerbositySummary = snapshots.VerbositySummary
+)
+
+// SetVerbosity sets how new and changed snapshots are reported for the current
+// test binary, overriding the SHUTTER_VERBOSITY environment variable.
+func SetVerbosity(v Verbosity) {
+	snapshots.SetVerbosity(v)
+}
+
+// SetOutput sends reports of new and changed snapshots to w. By default they
+// are attached to the log of the test that took the snapshot, so they only
+// show up for failing tests (or with -v) and stay with their test in
+// 'go test -json' output. A nil w restores the default.
+//
+// Example:
+//
+//	func TestMain(m *testing.M) {
+//	    shutter.SetOutput(os.Stderr)
+//	    os.Exit(m.Run())
+//	}
+func SetOutput(w io.Writer) {
+	snapshots.SetOutput(w)
+}
+
+// Main runs the tests of a package and then prints a summary of its new and
+// changed snapshots, with the exact command to review them. Call it from
+// TestMain with the *testing.M:
+//
+//	func TestMain(m *testing.M) {
+//	    os.Exit(shutter.Main(m))
+//	}
+//
+// When every test ran and passed, Main also marks the snapshots the run used
+// as complete, which Prune requires before deleting anything.
+func Main(m interface{ Run() int }) int {
+	code := m.Run()
+	if summary := snapshots.PackageSummary(); summary != "" {
+		fmt.Print("\n" + summary)
+	}
+	if code == 0 && !testsFiltered() {
+		if err := files.CompleteVisited(); err != nil {
+			fmt.Fprintf(os.Stderr, "shutter: failed to record the completed test run: %v\n", err)
+		}
+	}
+	return code
+}
+
+// testsFiltered reports whether the test binary was told to only run some of
+// its tests, in which case snapshots of the others were not visited.
+func testsFiltered() bool {
+	for _, name := range []string{"test.run", "test.skip", "test.list"} {
+		if f := flag.Lookup(name); f != nil && f.Value.String() != "" {
+			return true
+		}
+	}
+	f := flag.Lookup("test.short")
+	return f != nil && f.Value.String() == "true"
+}
+
+// Layout controls how snapshot files are arranged inside the snapshot directory.
+type Layout = files.Layout
+
+const (
+	// LayoutFlat stores every snapshot directly in the snapshot directory.
+	// This is the default.
+	LayoutFlat = files.LayoutFlat
+	// LayoutPerFile stores snapshots in one subdirectory per test file.
+	LayoutPerFile = files.LayoutPerFile
+	// LayoutNested stores snapshots in directories mirroring the t.Name()
+	// subtest hierarchy.
+	LayoutNested = files.LayoutNested
+)
+
+// ParseLayout parses a layout name: "flat", "per-file" or "nested".
+func ParseLayout(s string) (Layout, error) {
+	return files.ParseLayout(s)
+}
+
+// SetSnapshotDir sets the directory snapshots are stored in for the current
+// test binary, overriding the SHUTTER_SNAPSHOT_DIR environment variable and
+// the .shutter.yaml configuration file. Relative paths are resolved against
+// the package directory. The default is "__snapshots__".
+//
+// Example:
+//
+//	func TestMain(m *testing.M) {
+//	    shutter.SetSnapshotDir("testdata/snapshots")
+//	    os.Exit(m.Run())
+//	}
+//
+// Since the review commands cannot see settings made from code, prefer the
+// configuration file, or pass the same -dir flag to 'shutter review'.
+func SetSnapshotDir(dir string) {
+	files.SetSnapshotDir(dir)
+}
+
+// SetLayout sets the snapshot layout for the current test binary, overriding
+// the SHUTTER_LAYOUT environment variable and the .shutter.yaml configuration
+// file. Like SetSnapshotDir, the review commands need the matching -layout flag.
+func SetLayout(layout Layout) {
+	files.SetLayout(layout)
+}
+
+// Helper marks the calling function as a snapshot helper, like t.Helper does
+// for test failures. Snapshots taken through a helper record the file and line
+// of the helper's caller in their header instead of the helper's own.
+//
+// Example:
+//
+//	func snapUser(t *testing.T, user User) {
+//	    t.Helper()
+//	    shutter.Helper()
+//	    shutter.Snap(t, "", user, shutter.ScrubUUID())
+//	}
+func Helper() {
+	snapshots.MarkHelper(1)
 }
 
 // Review launches an interactive review session to accept or reject snapshot changes.
@@ -192,6 +597,23 @@ func RejectAll() error {
 	return review.RejectAll()
 }
 
+// Prune deletes snapshots that were not used by the most recent test run in
+// the current directory. When dryRun is true, obsolete snapshots are only listed.
+//
+// Every package using the snapshot directory must call Main from TestMain,
+// and its most recent run must have run all of its tests and passed. Prune
+// refuses to delete anything otherwise.
+func Prune(dryRun bool) error {
+	return review.Prune(dryRun)
+}
+
+// Migrate rewrites all snapshots in the current directory using the current
+// snapshot format and file naming scheme. When dryRun is true, the snapshots
+// that would change are only listed.
+func Migrate(dryRun bool) error {
+	return review.Migrate(dryRun)
+}
+
 // formatValue formats a single value using the configured utter instance.
 func formatValue(v any) string {
 	return utterConfig.Sdump(v)
@@ -206,20 +628,101 @@ func formatValues(values ...any) string {
 	return result
 }
 
-// separateOptions splits options into scrubbers and ignore patterns.
-func separateOptions(opts []Option) (scrubbers []Scrubber, ignores []IgnorePattern) {
+// formatSections formats the value of each section below its header line.
+func formatSections(sections []Section) (string, error) {
+	seen := map[string]bool{}
+	var sb strings.Builder
+	for _, section := range sections {
+		if strings.TrimSpace(section.Name) == "" || strings.ContainsAny(section.Name, "\r\n") {
+			return "", fmt.Errorf("invalid section name %q", section.Name)
+		}
+		if seen[section.Name] {
+			return "", fmt.Errorf("duplicate section name %q", section.Name)
+		}
+		seen[section.Name] = true
+
+		sb.WriteString(diff.SectionHeader(section.Name) + "\n")
+		sb.WriteString(formatValue(section.Value))
+	}
+	return sb.String(), nil
+}
+
+// snapOptions holds the options passed to a snapshot function, grouped by kind.
+type snapOptions struct {
+	scrubbers     []Scrubber
+	ignores       []IgnorePattern
+	titleTemplate string
+	callerSkip    int
+	info          snapshots.Info
+	includes      []*transform.PathPattern
+	arrayRules    []transform.ArrayRule
+	redactions    []transform.Redaction
+	// faithfulJSON and preserveKeyOrder only affect the SnapJSON functions.
+	faithfulJSON     bool
+	preserveKeyOrder bool
+}
+
+// jsonOnly names the kind of the given options that only work with the
+// SnapJSON functions, or returns an empty string if there are none.
+func (o snapOptions) jsonOnly() string {
+	switch {
+	case len(o.includes) > 0:
+		return "IncludeOnly"
+	case len(o.ignores) > 0:
+		return "IgnorePattern"
+	case len(o.arrayRules) > 0:
+		return "array"
+	case len(o.redactions) > 0:
+		return "Redaction"
+	case o.faithfulJSON:
+		return "FaithfulJSON"
+	case o.preserveKeyOrder:
+		return "PreserveKeyOrder"
+	}
+	return ""
+}
+
+// fileOnly names the kind of the given settings that only apply to snapshots
+// stored in files, or returns an empty string if there are none.
+func (o snapOptions) fileOnly() string {
+	switch {
+	case o.titleTemplate != "":
+		return "WithTitleTemplate"
+	case o.callerSkip != 0:
+		return "CallerSkip"
+	case o.info.Description != "":
+		return "WithDescription"
+	case len(o.info.Metadata) > 0:
+		return "WithMetadata"
+	}
+	return ""
+}
+
+// collectOptions groups options by kind.
+func collectOptions(opts []Option) snapOptions {
+	var result snapOptions
 	for _, opt := range opts {
 		switch o := opt.(type) {
 		case IgnorePattern:
-			ignores = append(ignores, o)
+			result.ignores = append(result.ignores, o)
 		case Scrubber:
-			scrubbers = append(scrubbers, o)
+			result.scrubbers = append(result.scrubbers, o)
+		case setting:
+			o.apply(&result)
 		default:
 			// This shouldn't happen if Option interface is properly implemented
 			panic(fmt.Sprintf("unknown option type: %T", opt))
 		}
 	}
-	return scrubbers, ignores
+	return result
+}
+
+// resolveTitle returns title, or a title derived from the test name if it is empty.
+func resolveTitle(t snapshots.T, title string, o snapOptions) string {
+	if title != "" {
+		return title
+	}
+	return snapshots.AutoTitle(t, o.titleTemplate)
 }
 
 // applyScrubbers applies all scrubbers to content in sequence.
@@ -257,9 +760,15 @@ func (i *ignoreAdapter) ShouldIgnore(key, value string) bool {
 }
 
 func toTransformIgnorePatterns(ignores []IgnorePattern) []transform.IgnorePattern {
-	result := make([]transform.IgnorePattern, len(ignores))
-	for i, ignore := range ignores {
-		result[i] = &ignoreAdapter{ignore: ignore}
+	result := make([]transform.IgnorePattern, 0, len(ignores))
+	for _, ignore := range ignores {
+		if p, ok := ignore.(*pathIgnore); ok {
+			for _, pattern := range p.patterns {
+				result = append(result, pattern)
+			}
+			continue
+		}
+		result = append(result, &ignoreAdapter{ignore: ignore})
 	}
 	return result
 }
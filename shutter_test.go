@@ -5,13 +5,19 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/ptdewey/shutter"
+	"github.com/ptdewey/shutter/internal/files"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(shutter.Main(m))
+}
+
 func TestSnapMultiple(t *testing.T) {
 	shutter.SnapMany(t, "Multiple Values Test", []any{"value1", "value2", 42, "foo", "bar", "baz", "wibble", "wobble", "tock", nil})
 }
@@ -33,6 +39,107 @@ func TestSnapCustomType(t *testing.T) {
 	shutter.Snap(t, "Custom Type Test", cs)
 }
 
+func TestCompare(t *testing.T) {
+	result, err := shutter.Compare("Custom Type Test", CustomStruct{Name: "Alice", Age: 30})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Status != shutter.StatusMatch {
+		t.Errorf("expected a match, got %s", result.Status)
+	}
+
+	result, err = shutter.Compare("Custom Type Test", CustomStruct{Name: "Bob", Age: 30})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Status != shutter.StatusChanged || result.Accepted == nil || len(result.Diff) == 0 {
+		t.Fatalf("expected a change with a diff, got %+v", result)
+	}
+	for _, dl := range result.Diff {
+		if dl.Kind == shutter.DiffNew && !strings.Contains(dl.Line, "Bob") {
+			t.Errorf("unexpected added line %q", dl.Line)
+		}
+	}
+
+	// Comparing never writes pending snapshots
+	pending, err := files.SnapshotPath(result.Name, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(pending); !os.IsNotExist(err) {
+		t.Errorf("expected no pending snapshot at %s, got %v", pending, err)
+	}
+}
+
+func TestCompare_NoSnapshotDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	result, err := shutter.Compare("Never Snapped", CustomStruct{Name: "Alice", Age: 30})
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	if result.Status != shutter.StatusNew {
+		t.Errorf("expected a new snapshot, got %s", result.Status)
+	}
+
+	// Comparing never creates the snapshot directory
+	if _, err := os.Stat("__snapshots__"); !os.IsNotExist(err) {
+		t.Errorf("expected no snapshot directory, got %v", err)
+	}
+}
+
+// compareThroughHelper compares a snapshot the way a shared test helper would.
+func compareThroughHelper(t *testing.T, value any) *shutter.Result {
+	t.Helper()
+	shutter.Helper()
+
+	result, err := shutter.Compare("Custom Type Test", value)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+	return result
+}
+
+func TestHelper(t *testing.T) {
+	result := compareThroughHelper(t, CustomStruct{Name: "Alice", Age: 30})
+	_, _, wantLine, _ := runtime.Caller(0)
+
+	if result.New.FileName != "shutter_test.go" || result.New.Line != wantLine-1 {
+		t.Errorf("location = %s:%d, want shutter_test.go:%d", result.New.FileName, result.New.Line, wantLine-1)
+	}
+	if want := `compareThroughHelper(t, CustomStruct{Name: "Alice", Age: 30})`; result.New.Expression != want {
+		t.Errorf("Expression = %q, want %q", result.New.Expression, want)
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	result, err := shutter.Compare("Custom Type Test", CustomStruct{Name: "Alice", Age: 30},
+		shutter.WithDescription("a custom formatted struct"),
+		shutter.WithMetadata(map[string]string{"name": "Alice"}),
+		shutter.WithMetadata(map[string]string{"age": "30"}),
+	)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	// Descriptive information is stored but never compared
+	if result.Status != shutter.StatusMatch {
+		t.Errorf("expected a match, got %s", result.Status)
+	}
+	if result.New.Description != "a custom formatted struct" {
+		t.Errorf("Description = %q", result.New.Description)
+	}
+	if len(result.New.Metadata) != 2 || result.New.Metadata["name"] != "Alice" || result.New.Metadata["age"] != "30" {
+		t.Errorf("Metadata = %v, want merged pairs", result.New.Metadata)
+	}
+}
+
+func TestCompareString_RequiresTitle(t *testing.T) {
+	if _, err := shutter.CompareString("", "content"); err == nil {
+		t.Error("expected an error for an empty title")
+	}
+}
+
 func contains(s, substr string) bool {
 	return strings.Contains(s, substr)
 }
@@ -630,3 +737,198 @@ func TestSnapJsonRealWorldExample(t *testing.T) {
 }
 
 func ptr[T any](t T) *T { return &t }
+
+func TestAutoTitle(t *testing.T) {
+	cases := []struct {
+		name  string
+		input CustomStruct
+	}{
+		{"alice", CustomStruct{Name: "Alice", Age: 30}},
+		{"bob", CustomStruct{Name: "Bob", Age: 25}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			shutter.Snap(t, "", tc.input)
+			shutter.SnapString(t, "", tc.input.Format(), shutter.WithTitleTemplate("{test}/{n}"))
+		})
+	}
+}
+
+func TestSnapInline(t *testing.T) {
+	shutter.SnapInline(t, "plain string", "plain string")
+	shutter.SnapInline(t, CustomStruct{Name: "Alice", Age: 30}, `
+		shutter_test.CustomStruct{
+		  Name: "Alice",
+		  Age: 30,
+		}
+	`)
+	shutter.SnapInline(t, "id: 550e8400-e29b-41d4-a716-446655440000", "id: <UUID>", shutter.ScrubUUID())
+}
+
+func TestSnapSections(t *testing.T) {
+	shutter.SnapSections(t, "Sections Test", []shutter.Section{
+		{Name: "request", Value: map[string]any{"method": "GET", "path": "/users/123"}},
+		{Name: "response", Value: CustomStruct{Name: "Alice", Age: 30}},
+		{Name: "status", Value: 200},
+	})
+}
+
+// errorRecorder records the errors reported through it instead of failing.
+type errorRecorder struct {
+	*testing.T
+	errors []string
+}
+
+func (r *errorRecorder) Error(args ...any) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func TestSnapInline_UnsupportedOptions(t *testing.T) {
+	tests := map[string]shutter.Option{
+		"IgnorePattern":     shutter.IgnoreKey("id"),
+		"WithTitleTemplate": shutter.WithTitleTemplate("{n}"),
+		"CallerSkip":        shutter.CallerSkip(1),
+		"WithDescription":   shutter.WithDescription("greeting"),
+		"WithMetadata":      shutter.WithMetadata(map[string]string{"input": "Alice"}),
+	}
+
+	for kind, opt := range tests {
+		rec := &errorRecorder{T: t}
+		shutter.SnapInline(rec, "Hello, Alice!", "Hello, Alice!", opt)
+		if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], kind+" options are not supported with SnapInline") {
+			t.Errorf("%s: expected an unsupported option error, got %v", kind, rec.errors)
+		}
+	}
+}
+
+func TestSnap_JSONOnlyOptions(t *testing.T) {
+	snapFuncs := map[string]func(t *errorRecorder, opt shutter.Option){
+		"Snap":       func(t *errorRecorder, opt shutter.Option) { shutter.Snap(t, "json only", 1, opt) },
+		"SnapString": func(t *errorRecorder, opt shutter.Option) { shutter.SnapString(t, "json only", "1", opt) },
+		"SnapMany":   func(t *errorRecorder, opt shutter.Option) { shutter.SnapMany(t, "json only", []any{1}, opt) },
+	}
+	options := map[string]shutter.Option{
+		"IncludeOnly":      shutter.IncludeOnlyKeys("a"),
+		"IgnorePattern":    shutter.IgnoreKey("a"),
+		"array":            shutter.SortArray("$"),
+		"Redaction":        shutter.RedactKey("id", "[id]"),
+		"FaithfulJSON":     shutter.FaithfulJSON(),
+		"PreserveKeyOrder": shutter.PreserveKeyOrder(),
+	}
+
+	for fn, snap := range snapFuncs {
+		for kind, opt := range options {
+			rec := &errorRecorder{T: t}
+			snap(rec, opt)
+			want := kind + " options are not supported with " + fn + ";"
+			if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], want) {
+				t.Errorf("%s with %s: expected an unsupported option error, got %v", fn, kind, rec.errors)
+			}
+		}
+	}
+}
+
+func TestJSONOptions_InvalidPathsPanic(t *testing.T) {
+	tests := []struct {
+		name   string
+		option func() shutter.Option
+	}{
+		{name: "IncludeOnly", option: func() shutter.Option { return shutter.IncludeOnly("user") }},
+		{name: "SortArray", option: func() shutter.Option { return shutter.SortArray("$.tags[") }},
+		{name: "SortArrayBy", option: func() shutter.Option { return shutter.SortArrayBy("$.users[", "id") }},
+		{name: "DropElements", option: func() shutter.Option {
+			return shutter.DropElements("events", func(any) bool { return true })
+		}},
+		{name: "Redact", option: func() shutter.Option { return shutter.Redact("users[*]", "x") }},
+		{name: "RedactWith", option: func() shutter.Option {
+			return shutter.RedactWith("$.", func(value any) any { return value })
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected %s to panic for an invalid path", tt.name)
+				}
+			}()
+			tt.option()
+		})
+	}
+}
+
+func TestSnapSections_InvalidNames(t *testing.T) {
+	tests := map[string][]shutter.Section{
+		"empty":     {{Name: "", Value: 1}},
+		"multiline": {{Name: "a\nb", Value: 1}},
+		"duplicate": {{Name: "a", Value: 1}, {Name: "a", Value: 2}},
+	}
+
+	for name, sections := range tests {
+		rec := &errorRecorder{T: t}
+		shutter.SnapSections(rec, "invalid sections "+name, sections)
+		if len(rec.errors) != 1 || !strings.Contains(rec.errors[0], "section name") {
+			t.Errorf("%s: expected a section name error, got %v", name, rec.errors)
+		}
+	}
+}
+
+type taggedUser struct {
+	ID       string `json:"id"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Internal string `json:"-"`
+	Nickname string `json:"nickname,omitempty"`
+}
+
+func TestSnapJSONValue(t *testing.T) {
+	user := taggedUser{
+		ID:       "550e8400-e29b-41d4-a716-446655440000",
+		Email:    "user@example.com",
+		Password: "secret",
+		Internal: "never marshaled",
+	}
+
+	shutter.SnapJSONValue(t, "JSON Value Test", user,
+		shutter.IgnoreKey("password"),
+		shutter.ScrubUUID(),
+	)
+}
+
+func TestSnapJSONBytes(t *testing.T) {
+	shutter.SnapJSONBytes(t, "JSON Bytes Test", []byte(`{"status":"ok","count":2}`))
+}
+
+func TestSnapJSONReader(t *testing.T) {
+	shutter.SnapJSONReader(t, "JSON Reader Test", strings.NewReader(`{"items":[1,2,3]}`))
+}
+
+// failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, fmt.Errorf("connection reset")
+}
+
+func TestSnapJSON_InputErrors(t *testing.T) {
+	rec := &errorRecorder{T: t}
+	shutter.SnapJSONReader(rec, "unreadable JSON", failingReader{})
+	shutter.SnapJSONValue(rec, "unmarshalable JSON", make(chan int))
+
+	if len(rec.errors) != 2 ||
+		!strings.Contains(rec.errors[0], "failed to read JSON: connection reset") ||
+		!strings.Contains(rec.errors[1], "failed to marshal JSON") {
+		t.Errorf("unexpected errors %q", rec.errors)
+	}
+}
+
+func TestSnapJSON_Faithful(t *testing.T) {
+	body := []byte(`{"id": 12345678901234567890, "price": 19.90, "note": "<b>Tom & Jerry</b>", "token": "secret", "created": 1.0e3}`)
+
+	shutter.SnapJSONBytes(t, "Faithful JSON Test", body,
+		shutter.FaithfulJSON(),
+		shutter.PreserveKeyOrder(),
+		shutter.IgnoreKey("token"),
+	)
+}